@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 
 	_ "github.com/lib/pq"
 	"github.com/project13/backend-stealthisproject/internal/database"
+	"github.com/project13/backend-stealthisproject/internal/layout"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
 )
 
 func main() {
@@ -68,6 +73,9 @@ func seedTrains(db *sql.DB) {
 }
 
 func seedCarriages(db *sql.DB) {
+	ctx := context.Background()
+	repos := repository.NewRepositories(db)
+
 	// Get train IDs
 	var trainIDs []int64
 	rows, err := db.Query("SELECT id FROM trains")
@@ -84,41 +92,63 @@ func seedCarriages(db *sql.DB) {
 		}
 	}
 
-	// Create carriages for each train
+	// Create carriages for each train from the carriage type templates
 	for _, trainID := range trainIDs {
-		carriageTypes := []struct {
-			number           int
-			carriageType     string
-			seatsPerCarriage int
+		carriages := []struct {
+			number   int
+			typeName string
 		}{
-			{1, "Плацкарт", 54},
-			{2, "Купе", 36},
-			{3, "СВ", 18},
+			{1, "Плацкарт"},
+			{2, "Купе"},
+			{3, "СВ"},
 		}
 
-		for _, ct := range carriageTypes {
-			var carriageID int64
-			err := db.QueryRow("SELECT id FROM carriages WHERE train_id = $1 AND number = $2", trainID, ct.number).Scan(&carriageID)
-			if err == sql.ErrNoRows {
-				err = db.QueryRow("INSERT INTO carriages (train_id, number, type) VALUES ($1, $2, $3) RETURNING id", trainID, ct.number, ct.carriageType).Scan(&carriageID)
-				if err != nil {
-					log.Printf("Failed to insert carriage: %v", err)
-					continue
-				}
-
-				// Create seats for this carriage
-				for i := 1; i <= ct.seatsPerCarriage; i++ {
-					_, err := db.Exec("INSERT INTO seats (carriage_id, number) VALUES ($1, $2)", carriageID, i)
-					if err != nil {
-						log.Printf("Failed to insert seat %d for carriage %d: %v", i, carriageID, err)
-					}
-				}
-				log.Printf("Inserted carriage %d (type: %s) with %d seats for train %d", ct.number, ct.carriageType, ct.seatsPerCarriage, trainID)
+		for _, ct := range carriages {
+			err := repos.WithTx(ctx, func(tx *repository.Repositories) error {
+				return seedCarriage(ctx, tx, trainID, ct.number, ct.typeName)
+			})
+			if err != nil {
+				log.Fatalf("Failed to seed carriage %d of train %d: %v", ct.number, trainID, err)
 			}
 		}
 	}
 }
 
+// seedCarriage builds the carriage from its type template and generates its
+// seats, as the admin carriage endpoint does; existing carriages are kept
+func seedCarriage(ctx context.Context, tx *repository.Repositories, trainID int64, number int, typeName string) error {
+	existing, err := tx.Carriage.GetByTrainID(ctx, trainID)
+	if err != nil {
+		return err
+	}
+	for _, carriage := range existing {
+		if carriage.Number == number {
+			return nil
+		}
+	}
+
+	carriageType, err := tx.CarriageType.GetByName(ctx, typeName)
+	if err != nil {
+		return err
+	}
+	if carriageType == nil {
+		return fmt.Errorf("carriage type %s not found", typeName)
+	}
+
+	carriage := &models.Carriage{TrainID: trainID, Number: number, TypeID: carriageType.ID, Type: *carriageType}
+	if err := tx.Carriage.Create(ctx, carriage); err != nil {
+		return err
+	}
+	seats := layout.ForType(carriage.Type).Seats(carriage.ID)
+	for i := range seats {
+		if err := tx.Seat.Create(ctx, &seats[i]); err != nil {
+			return fmt.Errorf("seat %d: %w", seats[i].Number, err)
+		}
+	}
+	log.Printf("Inserted carriage %d (type: %s) with %d seats for train %d", number, typeName, len(seats), trainID)
+	return nil
+}
+
 func seedSeats(db *sql.DB) {
 	// Seats are created in seedCarriages
 	log.Println("Seats created with carriages")
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/config"
+	"github.com/project13/backend-stealthisproject/internal/database"
+	"github.com/project13/backend-stealthisproject/internal/handlers"
+	"github.com/project13/backend-stealthisproject/internal/holds"
+	"github.com/project13/backend-stealthisproject/internal/mail"
+	"github.com/project13/backend-stealthisproject/internal/middleware"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+	"github.com/project13/backend-stealthisproject/pkg/auth"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take to drain
+const shutdownTimeout = 15 * time.Second
+
+// @title Railway Ticket System API
+// @version 1.0
+// @description Backend API for the Railway Ticket System
+// @BasePath /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+func main() {
+	cfg := config.Load()
+
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if err := database.RunMigrations(db); err != nil {
+		db.Close()
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	keyring, err := auth.LoadKeyring(cfg.JWTSecret, cfg.JWTKeys, cfg.JWTActiveKey)
+	if err != nil {
+		db.Close()
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		db.Close()
+		log.Fatalf("Failed to set up mail: %v", err)
+	}
+
+	repos := repository.NewRepositories(db)
+	authService := auth.NewAuthService(repos.User, keyring, cfg.AccessTokenTTL)
+	revocations := auth.NewRevocationList(repos.Session, cfg.RevocationSyncInterval)
+	h := handlers.NewHandlers(repos, authService, revocations, mailer, cfg)
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           setupRouter(h, middleware.AuthMiddleware(authService, revocations), cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	reaper := holds.NewReaper(repos.Order, cfg.HoldReaperInterval)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reaper.Run(ctx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		revocations.Run(ctx)
+	}()
+
+	go func() {
+		log.Printf("Server listening on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	h.Wait()
+	workers.Wait()
+
+	if err := db.Close(); err != nil {
+		log.Printf("Failed to close database: %v", err)
+	}
+
+	log.Println("Server stopped")
+}
+
+// newMailer returns the mailer MAIL_DRIVER selects
+func newMailer(cfg *config.Config) (mail.Mailer, error) {
+	switch cfg.MailDriver {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "log":
+		if cfg.MailLogFile == "" {
+			return mail.NewLogMailer(os.Stdout, cfg.MailFrom), nil
+		}
+		f, err := os.OpenFile(cfg.MailLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return mail.NewLogMailer(f, cfg.MailFrom), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+}
+
+func setupRouter(h *handlers.Handlers, authenticate gin.HandlerFunc, cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Logger(), gin.Recovery())
+	router.Use(middleware.CORS())
+	router.Use(middleware.ErrorHandler())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.GET("/swagger/*any", handlers.SwaggerHandler())
+	router.GET("/.well-known/jwks.json", h.GetJWKS)
+
+	api := router.Group("/api/v1")
+	api.Use(middleware.DBDeadline(cfg.DBTimeout))
+
+	authGroup := api.Group("/auth")
+	{
+		authGroup.POST("/register", h.Register)
+		authGroup.POST("/login", h.Login)
+		authGroup.POST("/refresh", h.Refresh)
+		authGroup.POST("/logout", h.Logout)
+		authGroup.POST("/verify-email", h.VerifyEmail)
+		authGroup.POST("/forgot-password", h.ForgotPassword)
+		authGroup.POST("/reset-password", h.ResetPassword)
+	}
+
+	routes := api.Group("/routes")
+	{
+		routes.GET("/search", h.SearchRoutes)
+		routes.GET("/journeys", h.SearchJourneys)
+		routes.GET("/:id", h.GetRoute)
+		routes.GET("/:id/seatmap", h.GetSeatMap)
+	}
+
+	protected := api.Group("")
+	protected.Use(authenticate)
+	{
+		protected.GET("/users/me", h.GetCurrentUser)
+		protected.PUT("/users/me", h.UpdateCurrentUser)
+		protected.PUT("/users/me/password", h.ChangePassword)
+		protected.POST("/users/me/verify-email", h.ResendVerificationEmail)
+		protected.GET("/users/me/sessions", h.GetSessions)
+		protected.DELETE("/users/me/sessions/:id", h.DeleteSession)
+
+		protected.POST("/orders", h.CreateOrder)
+		protected.GET("/orders", h.GetOrders)
+		protected.GET("/orders/:id", h.GetOrder)
+		protected.DELETE("/orders/:id", h.DeleteOrder)
+		protected.POST("/orders/:id/pay", h.PayOrder)
+		protected.POST("/orders/:id/refund", h.RefundOrder)
+		protected.GET("/orders/:id/history", h.GetOrderHistory)
+	}
+
+	admin := api.Group("/admin")
+	admin.Use(authenticate)
+	{
+		can := middleware.RequirePermission
+
+		admin.POST("/routes", can(models.PermRoutesWrite), h.CreateRoute)
+		admin.PUT("/routes/:id", can(models.PermRoutesWrite), h.UpdateRoute)
+		admin.DELETE("/routes/:id", can(models.PermRoutesWrite), h.DeleteRoute)
+		admin.GET("/routes/:id/stops", can(models.PermRoutesRead), h.GetRouteStops)
+		admin.POST("/routes/:id/stops", can(models.PermRoutesWrite), h.AddRouteStop)
+		admin.PUT("/routes/:id/stops", can(models.PermRoutesWrite), h.ReplaceRouteStops)
+		admin.PUT("/routes/:id/stops/:stationId", can(models.PermRoutesWrite), h.UpdateRouteStop)
+		admin.DELETE("/routes/:id/stops/:stationId", can(models.PermRoutesWrite), h.DeleteRouteStop)
+
+		admin.GET("/stations", can(models.PermStationsRead), h.GetStations)
+		admin.POST("/stations", can(models.PermStationsWrite), h.CreateStation)
+		admin.GET("/stations/:id", can(models.PermStationsRead), h.GetStation)
+		admin.PUT("/stations/:id", can(models.PermStationsWrite), h.UpdateStation)
+		admin.DELETE("/stations/:id", can(models.PermStationsWrite), h.DeleteStation)
+
+		admin.POST("/trains", can(models.PermTrainsWrite), h.CreateTrain)
+		admin.PUT("/trains/:id", can(models.PermTrainsWrite), h.UpdateTrain)
+		admin.DELETE("/trains/:id", can(models.PermTrainsWrite), h.DeleteTrain)
+		admin.GET("/trains/:id/carriages", can(models.PermTrainsRead), h.GetCarriages)
+		admin.POST("/trains/:id/carriages", can(models.PermTrainsWrite), h.CreateCarriage)
+		admin.GET("/trains/:id/carriages/:carriageId", can(models.PermTrainsRead), h.GetCarriage)
+		admin.PUT("/trains/:id/carriages/:carriageId", can(models.PermTrainsWrite), h.UpdateCarriage)
+		admin.DELETE("/trains/:id/carriages/:carriageId", can(models.PermTrainsWrite), h.DeleteCarriage)
+
+		admin.GET("/carriage-types", can(models.PermTrainsRead), h.GetCarriageTypes)
+		admin.POST("/carriage-types", can(models.PermTrainsWrite), h.CreateCarriageType)
+		admin.GET("/carriage-types/:id", can(models.PermTrainsRead), h.GetCarriageType)
+		admin.PUT("/carriage-types/:id", can(models.PermTrainsWrite), h.UpdateCarriageType)
+		admin.DELETE("/carriage-types/:id", can(models.PermTrainsWrite), h.DeleteCarriageType)
+
+		admin.GET("/calendars", can(models.PermCalendarsRead), h.GetCalendars)
+		admin.POST("/calendars", can(models.PermCalendarsWrite), h.CreateCalendar)
+		admin.GET("/calendars/holidays", can(models.PermCalendarsRead), h.GetHolidays)
+		admin.POST("/calendars/holidays", can(models.PermCalendarsWrite), h.SetHoliday)
+		admin.DELETE("/calendars/holidays/:date", can(models.PermCalendarsWrite), h.DeleteHoliday)
+		admin.GET("/calendars/:id", can(models.PermCalendarsRead), h.GetCalendar)
+		admin.PUT("/calendars/:id", can(models.PermCalendarsWrite), h.UpdateCalendar)
+		admin.DELETE("/calendars/:id", can(models.PermCalendarsWrite), h.DeleteCalendar)
+		admin.PUT("/calendars/:id/exceptions/:date", can(models.PermCalendarsWrite), h.SetCalendarException)
+		admin.DELETE("/calendars/:id/exceptions/:date", can(models.PermCalendarsWrite), h.DeleteCalendarException)
+
+		admin.GET("/orders", can(models.PermOrdersRead), h.GetAllOrders)
+		admin.GET("/tickets/:number", can(models.PermOrdersRead), h.GetTicket)
+
+		admin.GET("/users", can(models.PermUsersRead), h.GetUsers)
+		admin.GET("/users/:id", can(models.PermUsersRead), h.GetUser)
+		admin.PUT("/users/:id/roles", can(models.PermUsersWrite), h.SetUserRoles)
+		admin.GET("/roles", can(models.PermUsersRead), h.GetRoles)
+	}
+
+	return router
+}
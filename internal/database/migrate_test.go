@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("Failed to load embedded migrations: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Expected at least one embedded migration")
+	}
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("Migrations not strictly ordered: %d after %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestLoadMigrations_Sorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte("SELECT 2")},
+		"m/0002_second.down.sql": {Data: []byte("SELECT -2")},
+		"m/0001_first.up.sql":    {Data: []byte("SELECT 1")},
+		"m/0001_first.down.sql":  {Data: []byte("SELECT -1")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Name != "first" || migrations[1].Name != "second" {
+		t.Errorf("Unexpected order: %s, %s", migrations[0].Name, migrations[1].Name)
+	}
+	if migrations[1].Down != "SELECT -2" {
+		t.Errorf("Expected down SQL to be loaded, got %q", migrations[1].Down)
+	}
+}
+
+func TestLoadMigrations_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_first.up.sql": {Data: []byte("SELECT 1")},
+	}
+
+	if _, err := loadMigrations(fsys, "m"); err == nil {
+		t.Error("Expected error for migration without down file")
+	}
+}
+
+func TestLoadMigrations_InvalidName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/first.sql": {Data: []byte("SELECT 1")},
+	}
+
+	if _, err := loadMigrations(fsys, "m"); err == nil {
+		t.Error("Expected error for invalid migration file name")
+	}
+}
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS route_stations;
+DROP TABLE IF EXISTS routes;
+DROP TABLE IF EXISTS stations;
+DROP TABLE IF EXISTS seats;
+DROP TABLE IF EXISTS carriages;
+DROP TABLE IF EXISTS trains;
+DROP TABLE IF EXISTS passengers;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,84 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'PASSENGER',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS passengers (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    passport_data VARCHAR(50)
+);
+
+CREATE TABLE IF NOT EXISTS trains (
+    id BIGSERIAL PRIMARY KEY,
+    number VARCHAR(20) NOT NULL UNIQUE,
+    type VARCHAR(50)
+);
+
+CREATE TABLE IF NOT EXISTS carriages (
+    id BIGSERIAL PRIMARY KEY,
+    train_id BIGINT REFERENCES trains(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    type VARCHAR(50)
+);
+
+CREATE TABLE IF NOT EXISTS seats (
+    id BIGSERIAL PRIMARY KEY,
+    carriage_id BIGINT REFERENCES carriages(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    city VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS routes (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    train_id BIGINT REFERENCES trains(id) ON DELETE SET NULL,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 20.00
+);
+
+CREATE TABLE IF NOT EXISTS route_stations (
+    route_id BIGINT REFERENCES routes(id) ON DELETE CASCADE,
+    station_id BIGINT REFERENCES stations(id) ON DELETE CASCADE,
+    arrival_time TIME,
+    departure_time TIME,
+    stop_order INTEGER NOT NULL,
+    PRIMARY KEY (route_id, station_id)
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    route_id BIGINT REFERENCES routes(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    status VARCHAR(50) NOT NULL DEFAULT 'PENDING',
+    total_amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00
+);
+
+CREATE TABLE IF NOT EXISTS tickets (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT REFERENCES orders(id) ON DELETE CASCADE,
+    seat_id BIGINT REFERENCES seats(id),
+    passenger_id BIGINT REFERENCES passengers(id) ON DELETE SET NULL,
+    departure_date DATE NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    ticket_number VARCHAR(50) NOT NULL UNIQUE,
+    status VARCHAR(50) NOT NULL DEFAULT 'ACTIVE'
+);
+
+-- Databases created by the old boot-time migrations may predate these columns
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS route_id BIGINT REFERENCES routes(id) ON DELETE SET NULL;
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS price DECIMAL(10, 2) NOT NULL DEFAULT 20.00;
+
+CREATE INDEX IF NOT EXISTS idx_tickets_order_id ON tickets(order_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_passenger_id ON tickets(passenger_id);
+CREATE INDEX IF NOT EXISTS idx_route_stations_route_id ON route_stations(route_id);
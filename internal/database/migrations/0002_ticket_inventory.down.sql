@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tickets_route_date;
+DROP INDEX IF EXISTS uq_tickets_active_seat;
+ALTER TABLE tickets DROP COLUMN IF EXISTS route_id;
@@ -0,0 +1,24 @@
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS route_id BIGINT REFERENCES routes(id) ON DELETE SET NULL;
+
+UPDATE tickets t SET route_id = o.route_id
+FROM orders o
+WHERE t.order_id = o.id AND t.route_id IS NULL;
+
+-- Seats that were double-sold before the constraint existed keep only their
+-- earliest ticket active; the rest are cancelled so the index can be built
+UPDATE tickets t SET status = 'CANCELLED'
+WHERE t.status = 'ACTIVE'
+  AND EXISTS (
+      SELECT 1 FROM tickets d
+      WHERE d.status = 'ACTIVE'
+        AND d.route_id = t.route_id
+        AND d.seat_id = t.seat_id
+        AND d.departure_date = t.departure_date
+        AND d.id < t.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tickets_active_seat
+    ON tickets(route_id, departure_date, seat_id)
+    WHERE status = 'ACTIVE';
+
+CREATE INDEX IF NOT EXISTS idx_tickets_route_date ON tickets(route_id, departure_date);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_orders_pending_expires_at;
+
+UPDATE tickets SET status = 'ACTIVE' WHERE status = 'HELD';
+
+DROP INDEX IF EXISTS uq_tickets_active_seat;
+CREATE UNIQUE INDEX uq_tickets_active_seat
+    ON tickets(route_id, departure_date, seat_id)
+    WHERE status = 'ACTIVE';
+
+ALTER TABLE orders DROP COLUMN IF EXISTS expires_at;
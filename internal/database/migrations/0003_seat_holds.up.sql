@@ -0,0 +1,16 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Seats of unpaid orders become holds that the reaper releases
+UPDATE orders SET expires_at = created_at + INTERVAL '15 minutes'
+WHERE status = 'PENDING' AND expires_at IS NULL;
+
+UPDATE tickets t SET status = 'HELD'
+FROM orders o
+WHERE t.order_id = o.id AND o.status = 'PENDING' AND t.status = 'ACTIVE';
+
+DROP INDEX IF EXISTS uq_tickets_active_seat;
+CREATE UNIQUE INDEX uq_tickets_active_seat
+    ON tickets(route_id, departure_date, seat_id)
+    WHERE status IN ('HELD', 'ACTIVE');
+
+CREATE INDEX IF NOT EXISTS idx_orders_pending_expires_at ON orders(expires_at) WHERE status = 'PENDING';
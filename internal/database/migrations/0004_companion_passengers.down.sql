@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_passengers_user_id;
+ALTER TABLE passengers DROP COLUMN IF EXISTS is_primary;
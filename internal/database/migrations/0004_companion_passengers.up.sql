@@ -0,0 +1,8 @@
+-- A user's own profile is the primary passenger; further passengers of the
+-- same user are travel companions added while booking
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE passengers p SET is_primary = TRUE
+WHERE p.id = (SELECT MIN(id) FROM passengers WHERE user_id = p.user_id);
+
+CREATE INDEX IF NOT EXISTS idx_passengers_user_id ON passengers(user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS chk_tickets_status;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_status;
+DROP TABLE IF EXISTS order_status_history;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    changed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
+
+-- Existing orders start their history in their current state
+INSERT INTO order_status_history (order_id, to_status, changed_by, changed_at)
+SELECT id, status, user_id, created_at FROM orders;
+
+ALTER TABLE orders ADD CONSTRAINT chk_orders_status
+    CHECK (status IN ('PENDING', 'PAID', 'PARTIALLY_REFUNDED', 'REFUNDED', 'EXPIRED', 'CANCELLED'));
+
+ALTER TABLE tickets ADD CONSTRAINT chk_tickets_status
+    CHECK (status IN ('HELD', 'ACTIVE', 'EXPIRED', 'CANCELLED', 'REFUNDED'));
@@ -0,0 +1,7 @@
+ALTER TABLE seats
+    DROP COLUMN IF EXISTS reduced_mobility,
+    DROP COLUMN IF EXISTS wheelchair_space,
+    DROP COLUMN IF EXISTS direction,
+    DROP COLUMN IF EXISTS placement,
+    DROP COLUMN IF EXISTS compartment,
+    DROP COLUMN IF EXISTS position;
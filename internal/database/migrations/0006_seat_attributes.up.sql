@@ -0,0 +1,31 @@
+ALTER TABLE seats
+    ADD COLUMN IF NOT EXISTS position VARCHAR(20)
+        CONSTRAINT chk_seats_position CHECK (position IN ('LOWER', 'UPPER', 'SIDE_LOWER', 'SIDE_UPPER')),
+    ADD COLUMN IF NOT EXISTS compartment INTEGER,
+    ADD COLUMN IF NOT EXISTS placement VARCHAR(20)
+        CONSTRAINT chk_seats_placement CHECK (placement IN ('WINDOW', 'AISLE')),
+    ADD COLUMN IF NOT EXISTS direction VARCHAR(20)
+        CONSTRAINT chk_seats_direction CHECK (direction IN ('FORWARD', 'BACKWARD')),
+    ADD COLUMN IF NOT EXISTS wheelchair_space BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS reduced_mobility BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Sleeper carriages are numbered compartment by compartment, odd numbers on
+-- the lower berths. Плацкарт side berths 37-54 start opposite compartment 9.
+UPDATE seats s SET
+    compartment = CASE
+        WHEN c.type = 'СВ' THEN (s.number - 1) / 2 + 1
+        WHEN s.number <= 36 THEN (s.number - 1) / 4 + 1
+        ELSE 9 - (s.number - 37) / 2
+    END,
+    position = CASE
+        WHEN c.type = 'СВ' THEN 'LOWER'
+        WHEN s.number <= 36 AND s.number % 2 = 1 THEN 'LOWER'
+        WHEN s.number <= 36 THEN 'UPPER'
+        WHEN s.number % 2 = 1 THEN 'SIDE_LOWER'
+        ELSE 'SIDE_UPPER'
+    END
+FROM carriages c
+WHERE s.carriage_id = c.id
+  AND ((c.type = 'Плацкарт' AND s.number BETWEEN 1 AND 54)
+    OR (c.type = 'Купе' AND s.number BETWEEN 1 AND 36)
+    OR (c.type = 'СВ' AND s.number BETWEEN 1 AND 18));
@@ -0,0 +1,13 @@
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS excl_tickets_seat_segment;
+
+-- Fails if a seat has been sold on several segments of the same trip; cancel
+-- all but one of those tickets before rolling back
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tickets_active_seat
+    ON tickets(route_id, departure_date, seat_id)
+    WHERE status IN ('HELD', 'ACTIVE');
+
+ALTER TABLE tickets
+    DROP COLUMN IF EXISTS to_stop_order,
+    DROP COLUMN IF EXISTS from_stop_order,
+    DROP COLUMN IF EXISTS to_station_id,
+    DROP COLUMN IF EXISTS from_station_id;
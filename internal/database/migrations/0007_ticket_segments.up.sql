@@ -0,0 +1,36 @@
+-- A ticket covers the legs between its boarding and alighting stops. Stop
+-- orders are copied from route_stations so that overlapping tickets for the
+-- same seat can be excluded by a constraint.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE tickets
+    ADD COLUMN IF NOT EXISTS from_station_id BIGINT REFERENCES stations(id),
+    ADD COLUMN IF NOT EXISTS to_station_id BIGINT REFERENCES stations(id),
+    ADD COLUMN IF NOT EXISTS from_stop_order INTEGER,
+    ADD COLUMN IF NOT EXISTS to_stop_order INTEGER;
+
+-- Tickets sold so far cover their whole route
+UPDATE tickets t SET
+    from_station_id = first_stop.station_id,
+    from_stop_order = first_stop.stop_order,
+    to_station_id = last_stop.station_id,
+    to_stop_order = last_stop.stop_order
+FROM
+    (SELECT DISTINCT ON (route_id) route_id, station_id, stop_order
+     FROM route_stations ORDER BY route_id, stop_order) first_stop,
+    (SELECT DISTINCT ON (route_id) route_id, station_id, stop_order
+     FROM route_stations ORDER BY route_id, stop_order DESC) last_stop
+WHERE first_stop.route_id = t.route_id
+  AND last_stop.route_id = t.route_id
+  AND t.from_stop_order IS NULL;
+
+-- A seat is taken only on the legs a ticket covers. Tickets without stop
+-- orders get an unbounded range and keep blocking the whole route.
+DROP INDEX IF EXISTS uq_tickets_active_seat;
+ALTER TABLE tickets ADD CONSTRAINT excl_tickets_seat_segment
+    EXCLUDE USING gist (
+        route_id WITH =,
+        departure_date WITH =,
+        seat_id WITH =,
+        int4range(from_stop_order, to_stop_order) WITH &&
+    ) WHERE (status IN ('HELD', 'ACTIVE'));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_routes_calendar_id;
+ALTER TABLE routes DROP COLUMN IF EXISTS calendar_id;
+DROP TABLE IF EXISTS holidays;
+DROP TABLE IF EXISTS calendar_exceptions;
+DROP TABLE IF EXISTS service_calendars;
@@ -0,0 +1,36 @@
+-- A service calendar says on which days a route runs: a weekday pattern
+-- within an optional validity range, optionally skipping public holidays,
+-- with per-date exceptions that override everything else
+CREATE TABLE IF NOT EXISTS service_calendars (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    monday BOOLEAN NOT NULL DEFAULT FALSE,
+    tuesday BOOLEAN NOT NULL DEFAULT FALSE,
+    wednesday BOOLEAN NOT NULL DEFAULT FALSE,
+    thursday BOOLEAN NOT NULL DEFAULT FALSE,
+    friday BOOLEAN NOT NULL DEFAULT FALSE,
+    saturday BOOLEAN NOT NULL DEFAULT FALSE,
+    sunday BOOLEAN NOT NULL DEFAULT FALSE,
+    start_date DATE,
+    end_date DATE,
+    skip_holidays BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT chk_service_calendars_range CHECK (start_date IS NULL OR end_date IS NULL OR start_date <= end_date)
+);
+
+CREATE TABLE IF NOT EXISTS calendar_exceptions (
+    calendar_id BIGINT NOT NULL REFERENCES service_calendars(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL CONSTRAINT chk_calendar_exceptions_type CHECK (type IN ('ADDED', 'REMOVED')),
+    PRIMARY KEY (calendar_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS holidays (
+    date DATE PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+-- Routes without a calendar keep running every day
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS calendar_id BIGINT
+    CONSTRAINT fk_routes_calendar REFERENCES service_calendars(id);
+
+CREATE INDEX IF NOT EXISTS idx_routes_calendar_id ON routes(calendar_id);
@@ -0,0 +1,3 @@
+ALTER TABLE route_stations
+    DROP COLUMN IF EXISTS departure_day_offset,
+    DROP COLUMN IF EXISTS arrival_day_offset;
@@ -0,0 +1,37 @@
+-- Times of day alone can't tell a sleeper arriving at 06:30 the next morning
+-- from one arriving before it left. The day offsets count the days since the
+-- train left its first station.
+ALTER TABLE route_stations
+    ADD COLUMN IF NOT EXISTS arrival_day_offset INTEGER NOT NULL DEFAULT 0
+        CONSTRAINT chk_route_stations_arrival_day_offset CHECK (arrival_day_offset >= 0),
+    ADD COLUMN IF NOT EXISTS departure_day_offset INTEGER NOT NULL DEFAULT 0
+        CONSTRAINT chk_route_stations_departure_day_offset CHECK (departure_day_offset >= 0);
+
+-- Existing timetables roll over to the next day whenever a time is earlier
+-- than the one before it
+WITH events AS (
+    SELECT route_id, station_id, stop_order, 0 AS kind, arrival_time AS t
+    FROM route_stations WHERE arrival_time IS NOT NULL
+    UNION ALL
+    SELECT route_id, station_id, stop_order, 1 AS kind, departure_time AS t
+    FROM route_stations WHERE departure_time IS NOT NULL
+), rollovers AS (
+    SELECT route_id, station_id, kind,
+           SUM(CASE WHEN t < prev THEN 1 ELSE 0 END)
+               OVER (PARTITION BY route_id ORDER BY stop_order, kind) AS day_offset
+    FROM (
+        SELECT e.*, LAG(t) OVER (PARTITION BY route_id ORDER BY stop_order, kind) AS prev
+        FROM events e
+    ) ordered
+), offsets AS (
+    SELECT route_id, station_id,
+           MAX(day_offset) FILTER (WHERE kind = 0) AS arrival_day_offset,
+           MAX(day_offset) FILTER (WHERE kind = 1) AS departure_day_offset
+    FROM rollovers
+    GROUP BY route_id, station_id
+)
+UPDATE route_stations rs SET
+    arrival_day_offset = COALESCE(o.arrival_day_offset, o.departure_day_offset, 0),
+    departure_day_offset = COALESCE(o.departure_day_offset, o.arrival_day_offset, 0)
+FROM offsets o
+WHERE rs.route_id = o.route_id AND rs.station_id = o.station_id;
@@ -0,0 +1,4 @@
+ALTER TABLE tickets
+    DROP COLUMN IF EXISTS arrives_at,
+    DROP COLUMN IF EXISTS departs_at;
+ALTER TABLE stations DROP COLUMN IF EXISTS time_zone;
@@ -0,0 +1,19 @@
+-- Stop times are local times at their station. Existing stations are all in
+-- Belarus.
+ALTER TABLE stations ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'Europe/Minsk';
+
+-- A ticket keeps the absolute times of its segment, so that it doesn't
+-- depend on later timetable changes or on the zone of the reader
+ALTER TABLE tickets
+    ADD COLUMN IF NOT EXISTS departs_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS arrives_at TIMESTAMPTZ;
+
+UPDATE tickets t SET departs_at = (t.departure_date + rs.departure_day_offset + rs.departure_time) AT TIME ZONE s.time_zone
+FROM route_stations rs
+JOIN stations s ON s.id = rs.station_id
+WHERE rs.route_id = t.route_id AND rs.stop_order = t.from_stop_order AND rs.departure_time IS NOT NULL;
+
+UPDATE tickets t SET arrives_at = (t.departure_date + rs.arrival_day_offset + rs.arrival_time) AT TIME ZONE s.time_zone
+FROM route_stations rs
+JOIN stations s ON s.id = rs.station_id
+WHERE rs.route_id = t.route_id AND rs.stop_order = t.to_stop_order AND rs.arrival_time IS NOT NULL;
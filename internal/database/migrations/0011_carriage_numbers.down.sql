@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tickets_seat_id;
+DROP INDEX IF EXISTS uq_seats_carriage_number;
+DROP INDEX IF EXISTS uq_carriages_train_number;
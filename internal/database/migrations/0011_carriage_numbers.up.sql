@@ -0,0 +1,5 @@
+-- Carriages are managed over the API now; numbers identify them to
+-- passengers and must not repeat within a train, nor seats within a carriage
+CREATE UNIQUE INDEX IF NOT EXISTS uq_carriages_train_number ON carriages(train_id, number);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_seats_carriage_number ON seats(carriage_id, number);
+CREATE INDEX IF NOT EXISTS idx_tickets_seat_id ON tickets(seat_id);
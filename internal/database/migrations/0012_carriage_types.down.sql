@@ -0,0 +1,5 @@
+ALTER TABLE carriages ADD COLUMN IF NOT EXISTS type VARCHAR(50);
+UPDATE carriages c SET type = t.name FROM carriage_types t WHERE t.id = c.type_id;
+DROP INDEX IF EXISTS idx_carriages_type_id;
+ALTER TABLE carriages DROP COLUMN IF EXISTS type_id;
+DROP TABLE IF EXISTS carriage_types;
@@ -0,0 +1,42 @@
+-- A carriage type is the template carriages are built from: the layout their
+-- seats are generated from, the fare multiplier of the class and what it
+-- offers on board. Seats are numbered compartment by compartment, then the
+-- side berths, then open seats without a compartment.
+CREATE TABLE IF NOT EXISTS carriage_types (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    compartments INTEGER NOT NULL DEFAULT 0 CHECK (compartments >= 0),
+    seats_per_compartment INTEGER NOT NULL DEFAULT 0 CHECK (seats_per_compartment >= 0),
+    side_seats INTEGER NOT NULL DEFAULT 0 CHECK (side_seats >= 0),
+    open_seats INTEGER NOT NULL DEFAULT 0 CHECK (open_seats >= 0),
+    fare_multiplier DECIMAL(4, 2) NOT NULL DEFAULT 1 CHECK (fare_multiplier > 0),
+    amenities TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_carriage_types_name ON carriage_types(LOWER(name));
+
+INSERT INTO carriage_types (name, compartments, seats_per_compartment, side_seats, fare_multiplier, amenities) VALUES
+    ('Плацкарт', 9, 4, 18, 1.0, '{BEDDING}'),
+    ('Купе', 9, 4, 0, 1.5, '{BEDDING,AIR_CONDITIONING,POWER_OUTLETS}'),
+    ('СВ', 9, 2, 0, 2.5, '{BEDDING,AIR_CONDITIONING,POWER_OUTLETS,MEALS}')
+ON CONFLICT DO NOTHING;
+
+-- Carriages of any other class become open carriages sized to their seats
+INSERT INTO carriage_types (name, open_seats)
+SELECT MIN(COALESCE(NULLIF(TRIM(c.type), ''), 'Общий')), COALESCE(MAX(n.seats), 0)
+FROM carriages c
+LEFT JOIN (SELECT carriage_id, COUNT(*) AS seats FROM seats GROUP BY carriage_id) n ON n.carriage_id = c.id
+GROUP BY LOWER(COALESCE(NULLIF(TRIM(c.type), ''), 'Общий'))
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE carriages ADD COLUMN IF NOT EXISTS type_id BIGINT
+    CONSTRAINT fk_carriages_type REFERENCES carriage_types(id);
+
+UPDATE carriages c SET type_id = t.id
+FROM carriage_types t
+WHERE LOWER(t.name) = LOWER(COALESCE(NULLIF(TRIM(c.type), ''), 'Общий'));
+
+ALTER TABLE carriages ALTER COLUMN type_id SET NOT NULL;
+ALTER TABLE carriages DROP COLUMN IF EXISTS type;
+
+CREATE INDEX IF NOT EXISTS idx_carriages_type_id ON carriages(type_id);
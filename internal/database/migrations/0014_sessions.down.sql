@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS revoked_tokens;
+DELETE FROM refresh_tokens;
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS access_expires_at;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS access_jti;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_id;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id VARCHAR(32) NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+DROP TABLE IF EXISTS sessions;
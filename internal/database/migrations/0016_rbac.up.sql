@@ -0,0 +1,78 @@
+-- Staff access is granted through roles, each a set of permissions. Users
+-- without roles are passengers.
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL CONSTRAINT uq_roles_name UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    assigned_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('routes:read', 'View route stops'),
+    ('routes:write', 'Create, change and delete routes and their stops'),
+    ('stations:read', 'View stations'),
+    ('stations:write', 'Create, change and delete stations'),
+    ('trains:read', 'View carriages and carriage types'),
+    ('trains:write', 'Create, change and delete trains, carriages and carriage types'),
+    ('calendars:read', 'View service calendars and holidays'),
+    ('calendars:write', 'Change service calendars and holidays'),
+    ('orders:read', 'View every order and its history'),
+    ('users:read', 'View users and their roles'),
+    ('users:write', 'Assign roles to users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access'),
+    ('timetable-planner', 'Maintains routes, stations, trains and calendars'),
+    ('cashier', 'Sells tickets at the station'),
+    ('conductor', 'Checks tickets on board'),
+    ('finance', 'Reconciles orders and refunds'),
+    ('support', 'Helps passengers with their accounts and orders')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission FROM roles r
+JOIN (VALUES
+    ('admin', 'routes:read'), ('admin', 'routes:write'),
+    ('admin', 'stations:read'), ('admin', 'stations:write'),
+    ('admin', 'trains:read'), ('admin', 'trains:write'),
+    ('admin', 'calendars:read'), ('admin', 'calendars:write'),
+    ('admin', 'orders:read'), ('admin', 'users:read'), ('admin', 'users:write'),
+    ('timetable-planner', 'routes:read'), ('timetable-planner', 'routes:write'),
+    ('timetable-planner', 'stations:read'), ('timetable-planner', 'stations:write'),
+    ('timetable-planner', 'trains:read'), ('timetable-planner', 'trains:write'),
+    ('timetable-planner', 'calendars:read'), ('timetable-planner', 'calendars:write'),
+    ('cashier', 'routes:read'), ('cashier', 'stations:read'), ('cashier', 'trains:read'), ('cashier', 'orders:read'),
+    ('conductor', 'trains:read'), ('conductor', 'orders:read'),
+    ('finance', 'orders:read'),
+    ('support', 'orders:read'), ('support', 'users:read')
+) AS p(role, permission) ON p.role = r.name
+ON CONFLICT DO NOTHING;
+
+-- The old ADMIN role becomes the admin role
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u, roles r
+WHERE u.role = 'ADMIN' AND r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,4 @@
+-- 0017 only filled in attributes whose columns 0006 added, and carriages
+-- created since carry the same values, so they can't be told apart from data
+-- written later. The data stays; rolling back 0006 drops the columns.
+SELECT 1;
@@ -0,0 +1,49 @@
+-- Derive placement, direction and accessibility of existing seats from the
+-- layout of their carriage type, as layout.Seats does for new carriages.
+-- Berths face forward in the first half of their compartment; side berths
+-- lie along the window; open seats come in rows of four, window seats on the
+-- outside, facing the middle of the carriage. Types with the
+-- WHEELCHAIR_ACCESS amenity get accessible lower berths in the first
+-- compartment, or an accessible first row of open seats.
+WITH layout AS (
+    SELECT s.id,
+           s.number,
+           t.compartments,
+           t.seats_per_compartment,
+           t.compartments * t.seats_per_compartment AS in_compartments,
+           t.side_seats,
+           t.open_seats,
+           s.number - t.compartments * t.seats_per_compartment - t.side_seats - 1 AS open_index,
+           EXISTS (SELECT 1 FROM unnest(t.amenities) a WHERE UPPER(a) = 'WHEELCHAIR_ACCESS') AS accessible
+    FROM seats s
+    JOIN carriages c ON c.id = s.carriage_id
+    JOIN carriage_types t ON t.id = c.type_id
+)
+UPDATE seats s SET
+    placement = COALESCE(s.placement, CASE
+        WHEN l.number <= l.in_compartments THEN NULL
+        WHEN l.number <= l.in_compartments + l.side_seats THEN 'WINDOW'
+        WHEN l.open_index % 4 IN (0, 3) THEN 'WINDOW'
+        ELSE 'AISLE'
+    END),
+    direction = COALESCE(s.direction, CASE
+        WHEN l.number <= l.in_compartments THEN
+            CASE WHEN (l.number - 1) % l.seats_per_compartment >= (l.seats_per_compartment + 1) / 2
+                 THEN 'BACKWARD' ELSE 'FORWARD' END
+        WHEN l.number <= l.in_compartments + l.side_seats THEN NULL
+        WHEN l.open_index / 4 < ((l.open_seats + 3) / 4) / 2 THEN 'BACKWARD'
+        ELSE 'FORWARD'
+    END),
+    wheelchair_space = s.wheelchair_space OR (l.accessible AND CASE
+        WHEN l.compartments > 0 THEN l.number <= l.seats_per_compartment
+            AND (l.seats_per_compartment <= 2 OR l.number % 2 = 1)
+        ELSE l.number <= 2
+    END),
+    reduced_mobility = s.reduced_mobility OR (l.accessible AND CASE
+        WHEN l.compartments > 0 THEN l.number <= l.seats_per_compartment
+            AND (l.seats_per_compartment <= 2 OR l.number % 2 = 1)
+        ELSE l.number <= 4
+    END)
+FROM layout l
+WHERE s.id = l.id
+  AND l.number BETWEEN 1 AND l.in_compartments + l.side_seats + l.open_seats;
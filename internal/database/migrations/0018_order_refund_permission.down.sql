@@ -0,0 +1,6 @@
+UPDATE roles SET description = 'Sells tickets at the station' WHERE name = 'cashier';
+UPDATE roles SET description = 'Checks tickets on board' WHERE name = 'conductor';
+UPDATE roles SET description = 'Reconciles orders and refunds' WHERE name = 'finance';
+UPDATE roles SET description = 'Helps passengers with their accounts and orders' WHERE name = 'support';
+
+DELETE FROM permissions WHERE name = 'orders:refund';
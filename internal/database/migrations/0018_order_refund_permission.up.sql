@@ -0,0 +1,15 @@
+-- Finance refunds tickets of any order; the other roles only look orders up,
+-- which their descriptions now say
+INSERT INTO permissions (name, description) VALUES
+    ('orders:refund', 'Refund tickets of any order')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'orders:refund' FROM roles r
+WHERE r.name IN ('admin', 'finance')
+ON CONFLICT DO NOTHING;
+
+UPDATE roles SET description = 'Looks up timetables, trains and orders at the station' WHERE name = 'cashier';
+UPDATE roles SET description = 'Looks up trains and orders to check tickets on board' WHERE name = 'conductor';
+UPDATE roles SET description = 'Reviews orders and refunds tickets' WHERE name = 'finance';
+UPDATE roles SET description = 'Looks up passengers'' accounts and orders' WHERE name = 'support';
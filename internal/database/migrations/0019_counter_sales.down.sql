@@ -0,0 +1,4 @@
+UPDATE roles SET description = 'Looks up timetables, trains and orders at the station' WHERE name = 'cashier';
+UPDATE roles SET description = 'Looks up trains and orders to check tickets on board' WHERE name = 'conductor';
+
+DELETE FROM permissions WHERE name = 'orders:write';
@@ -0,0 +1,14 @@
+-- Cashiers sell tickets at the counter: they book, pay and cancel orders for
+-- passengers' accounts. Conductors check tickets on board by their number
+-- with orders:read, which they already hold.
+INSERT INTO permissions (name, description) VALUES
+    ('orders:write', 'Book, pay and cancel orders for any user')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'orders:write' FROM roles r
+WHERE r.name IN ('admin', 'cashier')
+ON CONFLICT DO NOTHING;
+
+UPDATE roles SET description = 'Sells tickets at the station' WHERE name = 'cashier';
+UPDATE roles SET description = 'Checks tickets on board' WHERE name = 'conductor';
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/mail"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+	"github.com/project13/backend-stealthisproject/pkg/auth"
+)
+
+// mailTimeout bounds the delivery of a single email
+const mailTimeout = 30 * time.Second
+
+// VerifyEmail confirms the user's email address
+// @Summary Verify email
+// @Description Confirm the email address with the token from the verification email. Each token works once.
+// @Tags Auth
+// @Accept json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /auth/verify-email [post]
+func (h *Handlers) VerifyEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		user, err := useAccountToken(ctx, tx, models.TokenVerifyEmail, req.Token)
+		if err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return tx.User.Update(ctx, user)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to verify email")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerificationEmail mails a new verification link
+// @Summary Resend verification email
+// @Description Mail the authenticated user a new email verification link; earlier links stop working
+// @Tags Users
+// @Security BearerAuth
+// @Success 202
+// @Failure 409 {object} map[string]string
+// @Router /users/me/verify-email [post]
+func (h *Handlers) ResendVerificationEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := h.repos.User.GetByID(ctx, c.GetInt64("user_id"))
+	if err != nil || user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := h.sendVerificationEmail(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ForgotPassword mails a password reset link
+// @Summary Forgot password
+// @Description Mail a password reset link to the address if an account uses it, unless one was mailed within the cooldown. The response is the same either way, so it doesn't reveal which addresses are registered.
+// @Tags Auth
+// @Accept json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 202
+// @Failure 400 {object} map[string]string
+// @Router /auth/forgot-password [post]
+func (h *Handlers) ForgotPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repos.User.GetByEmail(ctx, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password reset email"})
+		return
+	}
+	if user != nil {
+		if err := h.sendPasswordResetEmail(ctx, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password reset email"})
+			return
+		}
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a reset token
+// @Summary Reset password
+// @Description Set a new password with the token from the password reset email. Each token works once, and every session of the user is revoked.
+// @Tags Auth
+// @Accept json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /auth/reset-password [post]
+func (h *Handlers) ResetPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordHash, err := h.authService.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var revoked []models.RevokedToken
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		user, err := useAccountToken(ctx, tx, models.TokenResetPassword, req.Token)
+		if err != nil {
+			return err
+		}
+
+		user.PasswordHash = passwordHash
+		// The link arrived in the mailbox, which proves the address as well
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.User.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := tx.AccountToken.InvalidateByUserID(ctx, user.ID, models.TokenResetPassword); err != nil {
+			return err
+		}
+		revoked, err = tx.Session.RevokeAllByUserID(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to reset password")
+		return
+	}
+	h.revocations.Revoke(revoked...)
+
+	c.Status(http.StatusNoContent)
+}
+
+// useAccountToken spends a mailed token and returns its user
+func useAccountToken(ctx context.Context, tx *repository.Repositories, purpose models.TokenPurpose, token string) (*models.User, error) {
+	invalid := newHTTPError(http.StatusBadRequest, "Invalid or expired token")
+
+	stored, err := tx.AccountToken.GetByHash(ctx, purpose, auth.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.UsedAt != nil || !stored.ExpiresAt.After(time.Now()) {
+		return nil, invalid
+	}
+	used, err := tx.AccountToken.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, invalid
+	}
+
+	user, err := tx.User.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, invalid
+	}
+	return user, nil
+}
+
+func (h *Handlers) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := h.newAccountToken(ctx, user, models.TokenVerifyEmail, h.cfg.EmailVerificationTTL, 0)
+	if err != nil {
+		return err
+	}
+	h.deliver(mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Open the link below to confirm your email address:\n\n%s\n\nThe link works once and expires in %s.\n",
+			h.appLink("/verify-email", token), humanDuration(h.cfg.EmailVerificationTTL)),
+	})
+	return nil
+}
+
+// sendPasswordResetEmail mails a reset link unless one was mailed within the
+// cooldown, so the endpoint can't be used to flood an inbox
+func (h *Handlers) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := h.newAccountToken(ctx, user, models.TokenResetPassword, h.cfg.PasswordResetTTL, h.cfg.PasswordResetCooldown)
+	if err != nil || token == "" {
+		return err
+	}
+	h.deliver(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Open the link below to choose a new password:\n\n%s\n\nThe link works once and expires in %s. "+
+			"If you didn't ask to reset your password, ignore this email.\n",
+			h.appLink("/reset-password", token), humanDuration(h.cfg.PasswordResetTTL)),
+	})
+	return nil
+}
+
+// newAccountToken stores a new token for the purpose, replacing the user's
+// earlier ones, and returns it. Within cooldown of the previous token none is
+// stored and the returned token is empty.
+func (h *Handlers) newAccountToken(ctx context.Context, user *models.User, purpose models.TokenPurpose, ttl, cooldown time.Duration) (string, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return "", err
+	}
+	var issued bool
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		issued = false
+		if cooldown > 0 {
+			last, err := tx.AccountToken.LastCreatedAt(ctx, user.ID, purpose)
+			if err != nil {
+				return err
+			}
+			if time.Since(last) < cooldown {
+				return nil
+			}
+		}
+		if err := tx.AccountToken.InvalidateByUserID(ctx, user.ID, purpose); err != nil {
+			return err
+		}
+		err := tx.AccountToken.Create(ctx, &models.AccountToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		issued = err == nil
+		return err
+	})
+	if err != nil || !issued {
+		return "", err
+	}
+	return token, nil
+}
+
+func (h *Handlers) appLink(path, token string) string {
+	return strings.TrimRight(h.cfg.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// humanDuration spells a link lifetime for an email, e.g. "48 hours"
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return d.String()
+}
+
+// deliver sends msg in the background, so a slow mail server neither holds up
+// the request nor reveals through timing whether an account exists
+func (h *Handlers) deliver(msg mail.Message) {
+	h.deliveries.Add(1)
+	go func() {
+		defer h.deliveries.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q email: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// Wait blocks until the emails sent in the background are delivered or have
+// timed out; call it once the server stops taking requests
+func (h *Handlers) Wait() {
+	h.deliveries.Wait()
+}
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+	"github.com/project13/backend-stealthisproject/internal/schedule"
+)
+
+// routeRunsOn reports whether route runs on the service date. Routes without
+// a calendar run every day.
+func (h *Handlers) routeRunsOn(ctx context.Context, route *models.Route, date time.Time) (bool, error) {
+	if route.CalendarID == nil {
+		return true, nil
+	}
+	cal, err := h.repos.Calendar.GetByID(ctx, *route.CalendarID)
+	if err != nil {
+		return false, err
+	}
+	if cal == nil {
+		return true, nil
+	}
+	holiday, err := h.repos.Calendar.IsHoliday(ctx, date)
+	if err != nil {
+		return false, err
+	}
+	return cal.RunsOn(date, holiday), nil
+}
+
+// serviceDay loads every calendar at once and returns a check of whether a
+// route runs on date, for handlers that look at all routes
+func (h *Handlers) serviceDay(ctx context.Context, date time.Time) (func(*models.Route) bool, error) {
+	calendars, err := h.repos.Calendar.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	holiday, err := h.repos.Calendar.IsHoliday(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	runs := make(map[int64]bool, len(calendars))
+	for i := range calendars {
+		runs[calendars[i].ID] = calendars[i].RunsOn(date, holiday)
+	}
+	return func(route *models.Route) bool {
+		if route.CalendarID == nil {
+			return true
+		}
+		r, ok := runs[*route.CalendarID]
+		return r || !ok
+	}, nil
+}
+
+// checkCalendar returns a 400 error if the calendar does not exist
+func (h *Handlers) checkCalendar(ctx context.Context, id int64) error {
+	cal, err := h.repos.Calendar.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if cal == nil {
+		return newHTTPError(http.StatusBadRequest, "Calendar not found")
+	}
+	return nil
+}
+
+func calendarFromRequest(req *CalendarRequest) (*models.ServiceCalendar, error) {
+	cal := &models.ServiceCalendar{
+		Name:         req.Name,
+		Monday:       req.Monday,
+		Tuesday:      req.Tuesday,
+		Wednesday:    req.Wednesday,
+		Thursday:     req.Thursday,
+		Friday:       req.Friday,
+		Saturday:     req.Saturday,
+		Sunday:       req.Sunday,
+		SkipHolidays: req.SkipHolidays,
+	}
+	if req.StartDate != nil {
+		date, err := schedule.ParseDate(*req.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		cal.StartDate = &date
+	}
+	if req.EndDate != nil {
+		date, err := schedule.ParseDate(*req.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		cal.EndDate = &date
+	}
+	if cal.StartDate != nil && cal.EndDate != nil && cal.EndDate.Before(*cal.StartDate) {
+		return nil, errors.New("endDate must not be before startDate")
+	}
+	return cal, nil
+}
+
+func calendarResponse(cal *models.ServiceCalendar) CalendarResponse {
+	response := CalendarResponse{
+		ID:           cal.ID,
+		Name:         cal.Name,
+		Monday:       cal.Monday,
+		Tuesday:      cal.Tuesday,
+		Wednesday:    cal.Wednesday,
+		Thursday:     cal.Thursday,
+		Friday:       cal.Friday,
+		Saturday:     cal.Saturday,
+		Sunday:       cal.Sunday,
+		SkipHolidays: cal.SkipHolidays,
+		Exceptions:   []ExceptionResponse{},
+	}
+	if cal.StartDate != nil {
+		response.StartDate = cal.StartDate.Format(schedule.DateLayout)
+	}
+	if cal.EndDate != nil {
+		response.EndDate = cal.EndDate.Format(schedule.DateLayout)
+	}
+	for _, e := range cal.Exceptions {
+		response.Exceptions = append(response.Exceptions, ExceptionResponse{
+			Date: e.Date.Format(schedule.DateLayout),
+			Type: string(e.Type),
+		})
+	}
+	return response
+}
+
+// GetCalendars lists service calendars (requires calendars:read)
+// @Summary List calendars
+// @Description List service calendars with their exceptions (requires calendars:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} CalendarResponse
+// @Router /admin/calendars [get]
+func (h *Handlers) GetCalendars(c *gin.Context) {
+	calendars, err := h.repos.Calendar.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendars"})
+		return
+	}
+
+	responses := []CalendarResponse{}
+	for i := range calendars {
+		responses = append(responses, calendarResponse(&calendars[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetCalendar returns a service calendar (requires calendars:read)
+// @Summary Get calendar
+// @Description Get a service calendar with its exceptions (requires calendars:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Calendar ID"
+// @Success 200 {object} CalendarResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/calendars/{id} [get]
+func (h *Handlers) GetCalendar(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	cal, err := h.repos.Calendar.GetByID(c.Request.Context(), id)
+	if err != nil || cal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+	c.JSON(http.StatusOK, calendarResponse(cal))
+}
+
+// CreateCalendar creates a service calendar (requires calendars:write)
+// @Summary Create calendar
+// @Description Create a service calendar: the weekdays it runs on, an optional validity range and whether it skips holidays (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CalendarRequest true "Calendar data"
+// @Success 201 {object} CalendarResponse
+// @Failure 400 {object} map[string]string
+// @Router /admin/calendars [post]
+func (h *Handlers) CreateCalendar(c *gin.Context) {
+	var req CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cal, err := calendarFromRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repos.Calendar.Create(c.Request.Context(), cal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar"})
+		return
+	}
+	c.JSON(http.StatusCreated, calendarResponse(cal))
+}
+
+// UpdateCalendar replaces a service calendar (requires calendars:write)
+// @Summary Update calendar
+// @Description Replace the pattern and validity range of a service calendar; its exceptions are kept (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Calendar ID"
+// @Param request body CalendarRequest true "Calendar data"
+// @Success 200 {object} CalendarResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/calendars/{id} [put]
+func (h *Handlers) UpdateCalendar(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	var req CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cal, err := calendarFromRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := h.repos.Calendar.GetByID(ctx, id)
+	if err != nil || existing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+	cal.ID = id
+	cal.Exceptions = existing.Exceptions
+
+	if err := h.repos.Calendar.Update(ctx, cal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update calendar"})
+		return
+	}
+	c.JSON(http.StatusOK, calendarResponse(cal))
+}
+
+// DeleteCalendar deletes a service calendar (requires calendars:write)
+// @Summary Delete calendar
+// @Description Delete a service calendar that no route uses (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Calendar ID"
+// @Success 204
+// @Failure 409 {object} map[string]string
+// @Router /admin/calendars/{id} [delete]
+func (h *Handlers) DeleteCalendar(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+
+	if err := h.repos.Calendar.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrCalendarInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Calendar is used by routes"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetCalendarException adds or cancels service on a date (requires calendars:write)
+// @Summary Set calendar exception
+// @Description Make routes of the calendar run (ADDED) or not run (REMOVED) on a date regardless of the weekly pattern (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Calendar ID"
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Param request body ExceptionRequest true "Exception type"
+// @Success 200 {object} CalendarResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/calendars/{id}/exceptions/{date} [put]
+func (h *Handlers) SetCalendarException(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+	date, err := schedule.ParseDate(c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req ExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exceptionType := models.CalendarExceptionType(req.Type)
+	if exceptionType != models.CalendarDayAdded && exceptionType != models.CalendarDayRemoved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be ADDED or REMOVED"})
+		return
+	}
+
+	cal, err := h.repos.Calendar.GetByID(ctx, id)
+	if err != nil || cal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	exception := &models.CalendarException{CalendarID: id, Date: date, Type: exceptionType}
+	if err := h.repos.Calendar.SetException(ctx, exception); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set exception"})
+		return
+	}
+
+	cal, err = h.repos.Calendar.GetByID(ctx, id)
+	if err != nil || cal == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar"})
+		return
+	}
+	c.JSON(http.StatusOK, calendarResponse(cal))
+}
+
+// DeleteCalendarException removes an exception (requires calendars:write)
+// @Summary Delete calendar exception
+// @Description Let the weekly pattern decide again whether routes of the calendar run on a date (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Calendar ID"
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/calendars/{id}/exceptions/{date} [delete]
+func (h *Handlers) DeleteCalendarException(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return
+	}
+	date, err := schedule.ParseDate(c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deleted, err := h.repos.Calendar.DeleteException(c.Request.Context(), id, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exception"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exception not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetHolidays lists public holidays (requires calendars:read)
+// @Summary List holidays
+// @Description List the public holidays skipped by calendars that skip holidays (requires calendars:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} HolidayResponse
+// @Router /admin/calendars/holidays [get]
+func (h *Handlers) GetHolidays(c *gin.Context) {
+	holidays, err := h.repos.Calendar.GetHolidays(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get holidays"})
+		return
+	}
+
+	responses := []HolidayResponse{}
+	for _, holiday := range holidays {
+		responses = append(responses, HolidayResponse{Date: holiday.Date.Format(schedule.DateLayout), Name: holiday.Name})
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// SetHoliday adds a public holiday (requires calendars:write)
+// @Summary Add holiday
+// @Description Add a public holiday, or rename the one on the same date (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body HolidayRequest true "Holiday"
+// @Success 200 {object} HolidayResponse
+// @Failure 400 {object} map[string]string
+// @Router /admin/calendars/holidays [post]
+func (h *Handlers) SetHoliday(c *gin.Context) {
+	var req HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	date, err := schedule.ParseDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	holiday := &models.Holiday{Date: date, Name: req.Name}
+	if err := h.repos.Calendar.SetHoliday(c.Request.Context(), holiday); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add holiday"})
+		return
+	}
+	c.JSON(http.StatusOK, HolidayResponse{Date: req.Date, Name: req.Name})
+}
+
+// DeleteHoliday removes a public holiday (requires calendars:write)
+// @Summary Delete holiday
+// @Description Remove a public holiday (requires calendars:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /admin/calendars/holidays/{date} [delete]
+func (h *Handlers) DeleteHoliday(c *gin.Context) {
+	date, err := schedule.ParseDate(c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deleted, err := h.repos.Calendar.DeleteHoliday(c.Request.Context(), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete holiday"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Holiday not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
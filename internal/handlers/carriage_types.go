@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/layout"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+// GetCarriageTypes lists carriage types (requires trains:read)
+// @Summary List carriage types
+// @Description List the carriage types carriages are built from (requires trains:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} CarriageTypeResponse
+// @Router /admin/carriage-types [get]
+func (h *Handlers) GetCarriageTypes(c *gin.Context) {
+	types, err := h.repos.CarriageType.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get carriage types"})
+		return
+	}
+
+	responses := make([]CarriageTypeResponse, 0, len(types))
+	for _, t := range types {
+		responses = append(responses, carriageTypeResponse(t))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetCarriageType returns a carriage type (requires trains:read)
+// @Summary Get carriage type
+// @Description Get a carriage type with its layout, fare multiplier and amenities (requires trains:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Carriage type ID"
+// @Success 200 {object} CarriageTypeResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/carriage-types/{id} [get]
+func (h *Handlers) GetCarriageType(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid carriage type ID"})
+		return
+	}
+
+	carriageType, err := h.repos.CarriageType.GetByID(c.Request.Context(), id)
+	if err != nil || carriageType == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Carriage type not found"})
+		return
+	}
+	c.JSON(http.StatusOK, carriageTypeResponse(*carriageType))
+}
+
+// CreateCarriageType creates a carriage type (requires trains:write)
+// @Summary Create carriage type
+// @Description Create a carriage type: the layout seats are generated from, the fare multiplier of the class and its amenities (requires trains:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateCarriageTypeRequest true "Carriage type data"
+// @Success 201 {object} CarriageTypeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/carriage-types [post]
+func (h *Handlers) CreateCarriageType(c *gin.Context) {
+	var req CreateCarriageTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	carriageType := &models.CarriageType{
+		Name:                strings.TrimSpace(req.Name),
+		Compartments:        req.Compartments,
+		SeatsPerCompartment: req.SeatsPerCompartment,
+		SideSeats:           req.SideSeats,
+		OpenSeats:           req.OpenSeats,
+		FareMultiplier:      req.FareMultiplier,
+		Amenities:           uniqueTrimmed(req.Amenities),
+	}
+	if err := layout.ForType(*carriageType).Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repos.CarriageType.Create(c.Request.Context(), carriageType); err != nil {
+		if errors.Is(err, repository.ErrCarriageTypeNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Carriage type with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create carriage type"})
+		return
+	}
+	c.JSON(http.StatusCreated, carriageTypeResponse(*carriageType))
+}
+
+// UpdateCarriageType updates a carriage type (requires trains:write)
+// @Summary Update carriage type
+// @Description Update a carriage type. New fares use the changed multiplier; the layout can only change while no carriage is built from the type (requires trains:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Carriage type ID"
+// @Param request body UpdateCarriageTypeRequest true "Carriage type data"
+// @Success 200 {object} CarriageTypeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/carriage-types/{id} [put]
+func (h *Handlers) UpdateCarriageType(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid carriage type ID"})
+		return
+	}
+
+	var req UpdateCarriageTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var carriageType *models.CarriageType
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		var err error
+		carriageType, err = tx.CarriageType.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if carriageType == nil {
+			return newHTTPError(http.StatusNotFound, "Carriage type not found")
+		}
+
+		before := layout.ForType(*carriageType)
+		if name := strings.TrimSpace(req.Name); name != "" {
+			carriageType.Name = name
+		}
+		if req.Compartments != nil {
+			carriageType.Compartments = *req.Compartments
+		}
+		if req.SeatsPerCompartment != nil {
+			carriageType.SeatsPerCompartment = *req.SeatsPerCompartment
+		}
+		if req.SideSeats != nil {
+			carriageType.SideSeats = *req.SideSeats
+		}
+		if req.OpenSeats != nil {
+			carriageType.OpenSeats = *req.OpenSeats
+		}
+		if req.FareMultiplier != nil {
+			carriageType.FareMultiplier = *req.FareMultiplier
+		}
+		if req.Amenities != nil {
+			carriageType.Amenities = uniqueTrimmed(req.Amenities)
+		}
+
+		after := layout.ForType(*carriageType)
+		if err := after.Validate(); err != nil {
+			return newHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if after != before {
+			used, err := tx.CarriageType.HasCarriages(ctx, id)
+			if err != nil {
+				return err
+			}
+			if used {
+				return newHTTPError(http.StatusConflict, "Carriages are built from this type and its layout can't change")
+			}
+		}
+
+		if err := tx.CarriageType.Update(ctx, carriageType); err != nil {
+			if errors.Is(err, repository.ErrCarriageTypeNameTaken) {
+				return newHTTPError(http.StatusConflict, "Carriage type with this name already exists")
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update carriage type")
+		return
+	}
+
+	c.JSON(http.StatusOK, carriageTypeResponse(*carriageType))
+}
+
+// DeleteCarriageType deletes a carriage type (requires trains:write)
+// @Summary Delete carriage type
+// @Description Delete a carriage type that no carriage is built from (requires trains:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Carriage type ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/carriage-types/{id} [delete]
+func (h *Handlers) DeleteCarriageType(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid carriage type ID"})
+		return
+	}
+
+	carriageType, err := h.repos.CarriageType.GetByID(ctx, id)
+	if err != nil || carriageType == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Carriage type not found"})
+		return
+	}
+
+	if err := h.repos.CarriageType.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrCarriageTypeInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Carriage type is used by carriages"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete carriage type"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func carriageTypeResponse(t models.CarriageType) CarriageTypeResponse {
+	if t.Amenities == nil {
+		t.Amenities = []string{}
+	}
+	return CarriageTypeResponse{CarriageType: t, Capacity: layout.ForType(t).Capacity()}
+}
+
+// uniqueTrimmed trims the listed names and drops blank and repeated ones
+func uniqueTrimmed(list []string) []string {
+	result := []string{}
+	seen := make(map[string]bool, len(list))
+	for _, a := range list {
+		a = strings.TrimSpace(a)
+		if a == "" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		result = append(result, a)
+	}
+	return result
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/layout"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+// GetCarriages lists the carriages of a train (requires trains:read)
+// @Summary List carriages
+// @Description List the carriages of a train with their seats (requires trains:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Train ID"
+// @Success 200 {array} CarriageResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/trains/{id}/carriages [get]
+func (h *Handlers) GetCarriages(c *gin.Context) {
+	ctx := c.Request.Context()
+	trainID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid train ID"})
+		return
+	}
+
+	train, err := h.repos.Train.GetByID(ctx, trainID)
+	if err != nil || train == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Train not found"})
+		return
+	}
+	carriages, err := h.repos.Carriage.GetByTrainID(ctx, trainID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get carriages"})
+		return
+	}
+
+	responses := []CarriageResponse{}
+	for i := range carriages {
+		response, err := carriageResponse(ctx, h.repos, &carriages[i])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get seats"})
+			return
+		}
+		responses = append(responses, response)
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetCarriage returns a carriage of a train (requires trains:read)
+// @Summary Get carriage
+// @Description Get a carriage of a train with its seats (requires trains:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Train ID"
+// @Param carriageId path int true "Carriage ID"
+// @Success 200 {object} CarriageResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/trains/{id}/carriages/{carriageId} [get]
+func (h *Handlers) GetCarriage(c *gin.Context) {
+	ctx := c.Request.Context()
+	carriage, err := trainCarriage(ctx, h.repos, c)
+	if err != nil {
+		respondError(c, err, "Failed to get carriage")
+		return
+	}
+
+	response, err := carriageResponse(ctx, h.repos, carriage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get seats"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateCarriage adds a carriage to a train (requires trains:write)
+// @Summary Create carriage
+// @Description Add a carriage to a train and generate its seats from the layout of its carriage type (requires trains:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Train ID"
+// @Param request body CreateCarriageRequest true "Carriage data"
+// @Success 201 {object} CarriageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/trains/{id}/carriages [post]
+func (h *Handlers) CreateCarriage(c *gin.Context) {
+	ctx := c.Request.Context()
+	trainID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid train ID"})
+		return
+	}
+
+	var req CreateCarriageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var response CarriageResponse
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		train, err := tx.Train.GetByID(ctx, trainID)
+		if err != nil {
+			return err
+		}
+		if train == nil {
+			return newHTTPError(http.StatusNotFound, "Train not found")
+		}
+
+		carriageType, err := tx.CarriageType.GetByID(ctx, req.TypeID)
+		if err != nil {
+			return err
+		}
+		if carriageType == nil {
+			return newHTTPError(http.StatusBadRequest, "Carriage type not found")
+		}
+
+		carriage := &models.Carriage{TrainID: trainID, Number: req.Number, TypeID: carriageType.ID, Type: *carriageType}
+		if err := tx.Carriage.Create(ctx, carriage); err != nil {
+			if errors.Is(err, repository.ErrCarriageNumberTaken) {
+				return newHTTPError(http.StatusConflict, "Train already has a carriage with this number")
+			}
+			return err
+		}
+		if err := generateSeats(ctx, tx, carriage); err != nil {
+			return err
+		}
+		response, err = carriageResponse(ctx, tx, carriage)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create carriage")
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateCarriage renumbers a carriage or changes its type (requires trains:write)
+// @Summary Update carriage
+// @Description Renumber a carriage or change its type, which regenerates its seats. Carriages with held or sold tickets can't be renumbered and carriages with any tickets can't change type (requires trains:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Train ID"
+// @Param carriageId path int true "Carriage ID"
+// @Param request body UpdateCarriageRequest true "Carriage data"
+// @Success 200 {object} CarriageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/trains/{id}/carriages/{carriageId} [put]
+func (h *Handlers) UpdateCarriage(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req UpdateCarriageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var response CarriageResponse
+	err := h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		carriage, err := trainCarriage(ctx, tx, c)
+		if err != nil {
+			return err
+		}
+
+		renumber := req.Number != 0 && req.Number != carriage.Number
+		retype := req.TypeID != 0 && req.TypeID != carriage.TypeID
+		if renumber {
+			sold, err := tx.Carriage.HasSoldTickets(ctx, carriage.ID)
+			if err != nil {
+				return err
+			}
+			if sold {
+				return newHTTPError(http.StatusConflict, "Carriage has sold tickets and can't be renumbered")
+			}
+			carriage.Number = req.Number
+		}
+		if retype {
+			carriageType, err := tx.CarriageType.GetByID(ctx, req.TypeID)
+			if err != nil {
+				return err
+			}
+			if carriageType == nil {
+				return newHTTPError(http.StatusBadRequest, "Carriage type not found")
+			}
+			carriage.TypeID, carriage.Type = carriageType.ID, *carriageType
+		}
+
+		if err := tx.Carriage.Update(ctx, carriage); err != nil {
+			if errors.Is(err, repository.ErrCarriageNumberTaken) {
+				return newHTTPError(http.StatusConflict, "Train already has a carriage with this number")
+			}
+			return err
+		}
+		if retype {
+			if err := tx.Seat.DeleteByCarriageID(ctx, carriage.ID); err != nil {
+				if errors.Is(err, repository.ErrCarriageInUse) {
+					return newHTTPError(http.StatusConflict, "Carriage has tickets and its seats can't be regenerated")
+				}
+				return err
+			}
+			if err := generateSeats(ctx, tx, carriage); err != nil {
+				return err
+			}
+		}
+		response, err = carriageResponse(ctx, tx, carriage)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update carriage")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteCarriage removes a carriage from a train (requires trains:write)
+// @Summary Delete carriage
+// @Description Remove a carriage and its seats from a train. Carriages with tickets can't be removed (requires trains:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Train ID"
+// @Param carriageId path int true "Carriage ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/trains/{id}/carriages/{carriageId} [delete]
+func (h *Handlers) DeleteCarriage(c *gin.Context) {
+	ctx := c.Request.Context()
+	err := h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		carriage, err := trainCarriage(ctx, tx, c)
+		if err != nil {
+			return err
+		}
+
+		sold, err := tx.Carriage.HasSoldTickets(ctx, carriage.ID)
+		if err != nil {
+			return err
+		}
+		if sold {
+			return newHTTPError(http.StatusConflict, "Carriage has sold tickets and can't be removed")
+		}
+		if err := tx.Carriage.Delete(ctx, carriage.ID); err != nil {
+			if errors.Is(err, repository.ErrCarriageInUse) {
+				return newHTTPError(http.StatusConflict, "Carriage has tickets and can't be removed")
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete carriage")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// trainCarriage loads the carriage named by the carriageId path parameter
+// and checks that it belongs to the train in the id parameter
+func trainCarriage(ctx context.Context, repos *repository.Repositories, c *gin.Context) (*models.Carriage, error) {
+	trainID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "Invalid train ID")
+	}
+	carriageID, err := strconv.ParseInt(c.Param("carriageId"), 10, 64)
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "Invalid carriage ID")
+	}
+
+	carriage, err := repos.Carriage.GetByID(ctx, carriageID)
+	if err != nil {
+		return nil, err
+	}
+	if carriage == nil || carriage.TrainID != trainID {
+		return nil, newHTTPError(http.StatusNotFound, "Carriage not found")
+	}
+	return carriage, nil
+}
+
+// generateSeats creates the seats of a new or retyped carriage from the
+// layout of its type
+func generateSeats(ctx context.Context, repos *repository.Repositories, carriage *models.Carriage) error {
+	seats := layout.ForType(carriage.Type).Seats(carriage.ID)
+	for i := range seats {
+		if err := repos.Seat.Create(ctx, &seats[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func carriageResponse(ctx context.Context, repos *repository.Repositories, carriage *models.Carriage) (CarriageResponse, error) {
+	seats, err := repos.Seat.GetByCarriageID(ctx, carriage.ID)
+	if err != nil {
+		return CarriageResponse{}, err
+	}
+	if seats == nil {
+		seats = []models.Seat{}
+	}
+	return CarriageResponse{
+		ID:      carriage.ID,
+		TrainID: carriage.TrainID,
+		Number:  carriage.Number,
+		Type:    carriage.Type,
+		Seats:   seats,
+	}, nil
+}
@@ -1,29 +1,89 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/layout"
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Password  string `json:"password" binding:"required,min=8"`
 	FirstName string `json:"firstName" binding:"required"`
 	LastName  string `json:"lastName" binding:"required"`
+	// Device names the session in the list of the user's sessions
+	Device string `json:"device" binding:"max=255"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// Device names the session in the list of the user's sessions
+	Device string `json:"device" binding:"max=255"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8"`
+}
+
+// SessionResponse is a login of the user; Current marks the session of the
+// token the request was made with
+type SessionResponse struct {
+	ID         int64     `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
 }
 
+// AuthResponse carries a short-lived access token, valid for ExpiresIn
+// seconds, and the refresh token to get the next one with
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  *UserResponse `json:"user"`
+	Token        string        `json:"token"`
+	RefreshToken string        `json:"refreshToken"`
+	ExpiresIn    int           `json:"expiresIn"`
+	User         *UserResponse `json:"user,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// VerifyEmailRequest carries the token of a verification email
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
+// SetUserRolesRequest names every role the user should have; an empty list
+// makes the user a passenger
+type SetUserRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest carries the token of a password reset email
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=8"`
+}
+
+// UserResponse is a user's profile; Roles is empty for passengers
 type UserResponse struct {
-	ID           int64  `json:"id"`
-	Email        string `json:"email"`
-	FirstName    string `json:"firstName,omitempty"`
-	LastName     string `json:"lastName,omitempty"`
-	PassportData string `json:"passportData,omitempty"`
-	Role         string `json:"role"`
+	ID            int64    `json:"id"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"emailVerified"`
+	FirstName     string   `json:"firstName,omitempty"`
+	LastName      string   `json:"lastName,omitempty"`
+	PassportData  string   `json:"passportData,omitempty"`
+	Roles         []string `json:"roles"`
 }
 
 type UpdateUserRequest struct {
@@ -33,19 +93,92 @@ type UpdateUserRequest struct {
 }
 
 type RouteSearchResponse struct {
-	RouteID       int64   `json:"routeId"`
-	TrainNumber   string  `json:"trainNumber"`
-	DepartureTime string  `json:"departureTime"`
-	ArrivalTime   string  `json:"arrivalTime"`
-	Price         float64 `json:"price"`
-	AvailableSeats int    `json:"availableSeats"`
+	RouteID         int64   `json:"routeId"`
+	TrainNumber     string  `json:"trainNumber"`
+	FromStationID   int64   `json:"fromStationId"`
+	ToStationID     int64   `json:"toStationId"`
+	DepartureTime   string  `json:"departureTime"`
+	ArrivalTime     string  `json:"arrivalTime"`
+	DurationMinutes int     `json:"durationMinutes"`
+	Price           float64 `json:"price"`
+	AvailableSeats  int     `json:"availableSeats"`
+}
+
+// JourneyResponse is an itinerary from the journey planner. It can be booked
+// as one order by passing each leg's route and stations as the order's legs.
+type JourneyResponse struct {
+	DepartureTime   string               `json:"departureTime"`
+	ArrivalTime     string               `json:"arrivalTime"`
+	DurationMinutes int                  `json:"durationMinutes"`
+	Transfers       int                  `json:"transfers"`
+	Price           float64              `json:"price"`
+	Legs            []JourneyLegResponse `json:"legs"`
+}
+
+type JourneyLegResponse struct {
+	RouteID        int64   `json:"routeId"`
+	TrainNumber    string  `json:"trainNumber"`
+	FromStationID  int64   `json:"fromStationId"`
+	FromStation    string  `json:"fromStation"`
+	ToStationID    int64   `json:"toStationId"`
+	ToStation      string  `json:"toStation"`
+	DepartureTime  string  `json:"departureTime"`
+	ArrivalTime    string  `json:"arrivalTime"`
+	Price          float64 `json:"price"`
+	AvailableSeats int     `json:"availableSeats"`
 }
 
 type CreateOrderRequest struct {
-	RouteID     int64   `json:"routeId" binding:"required"`
-	SeatID      int64   `json:"seatId" binding:"required"`
-	Price       float64 `json:"price" binding:"required"`
-	PassengerID *int64  `json:"passengerId"`
+	// DepartureDate is the travel date in YYYY-MM-DD format
+	DepartureDate string `json:"departureDate" binding:"required"`
+	// UserID books the order for another account, e.g. a passenger at the
+	// ticket counter; it needs orders:write
+	UserID *int64 `json:"userId"`
+	// Legs books a journey with transfers, one leg per train. When empty,
+	// the order is for RouteID alone.
+	Legs []OrderLegRequest `json:"legs" binding:"omitempty,max=5,dive"`
+
+	RouteID int64 `json:"routeId"`
+	// FromStationID and ToStationID bound the booked segment; they default to
+	// the first and the last stop of the route
+	FromStationID *int64             `json:"fromStationId"`
+	ToStationID   *int64             `json:"toStationId"`
+	Items         []OrderItemRequest `json:"items" binding:"omitempty,max=10,dive"`
+
+	// Single-seat form, used when Items is empty
+	SeatID            int64    `json:"seatId"`
+	PassengerID       *int64   `json:"passengerId"`
+	PassengerCategory string   `json:"passengerCategory"`
+	ExpectedPrice     *float64 `json:"price"`
+}
+
+// OrderLegRequest is the part of a journey travelled on one route, with the
+// seats booked on it. The stations default to the first and last stop.
+type OrderLegRequest struct {
+	RouteID       int64              `json:"routeId" binding:"required"`
+	FromStationID *int64             `json:"fromStationId"`
+	ToStationID   *int64             `json:"toStationId"`
+	Items         []OrderItemRequest `json:"items" binding:"required,min=1,max=10,dive"`
+}
+
+// OrderItemRequest is one seat of an order. The traveller is either one of
+// the user's saved passengers (PassengerID), a new passenger described
+// inline (Passenger) or, when both are empty, the user themselves.
+type OrderItemRequest struct {
+	SeatID      int64             `json:"seatId" binding:"required"`
+	PassengerID *int64            `json:"passengerId"`
+	Passenger   *PassengerRequest `json:"passenger"`
+	// PassengerCategory is ADULT (default), CHILD, STUDENT or SENIOR
+	PassengerCategory string `json:"passengerCategory"`
+	// ExpectedPrice is the fare the client showed to the user. The fare is
+	// always computed on the server; a mismatch is rejected with 409.
+	ExpectedPrice *float64 `json:"price"`
+}
+
+type PassengerRequest struct {
+	FirstName    string `json:"firstName" binding:"required"`
+	LastName     string `json:"lastName" binding:"required"`
+	PassportData string `json:"passportData"`
 }
 
 type OrderResponse struct {
@@ -60,6 +193,7 @@ type OrderResponse struct {
 	DepartureTime string      `json:"departureTime,omitempty"`
 	ArrivalTime string        `json:"arrivalTime,omitempty"`
 	CreatedAt  string         `json:"createdAt"`
+	ExpiresAt  string         `json:"expiresAt,omitempty"`
 	Status     string         `json:"status"`
 	TotalAmount float64       `json:"totalAmount"`
 	Tickets    []TicketResponse `json:"tickets"`
@@ -68,11 +202,59 @@ type OrderResponse struct {
 type TicketResponse struct {
 	ID           int64   `json:"id"`
 	TicketNumber string  `json:"ticketNumber"`
+	RouteID      *int64  `json:"routeId,omitempty"`
+	TrainNumber  string  `json:"trainNumber,omitempty"`
+	PassengerID  *int64  `json:"passengerId,omitempty"`
+	PassengerName string `json:"passengerName,omitempty"`
 	SeatNumber   *int    `json:"seatNumber"`
 	CarriageNumber *int   `json:"carriageNumber"`
+	DepartureDate string  `json:"departureDate"`
+	FromStationID *int64  `json:"fromStationId,omitempty"`
+	FromStation  string  `json:"fromStation,omitempty"`
+	ToStationID  *int64  `json:"toStationId,omitempty"`
+	ToStation    string  `json:"toStation,omitempty"`
+	// DepartureTime and ArrivalTime are RFC 3339 in the local time of the
+	// boarding and alighting stations
+	DepartureTime string `json:"departureTime,omitempty"`
+	ArrivalTime  string  `json:"arrivalTime,omitempty"`
+	Status       string  `json:"status"`
 	Price        float64 `json:"price"`
 }
 
+// Seat states shown on the seat map
+const (
+	SeatFree = "FREE"
+	SeatHeld = "HELD"
+	SeatSold = "SOLD"
+)
+
+type SeatMapResponse struct {
+	RouteID       int64             `json:"routeId"`
+	TrainNumber   string            `json:"trainNumber"`
+	DepartureDate string            `json:"departureDate"`
+	FromStationID int64             `json:"fromStationId"`
+	ToStationID   int64             `json:"toStationId"`
+	Carriages     []SeatMapCarriage `json:"carriages"`
+}
+
+// SeatMapCarriage is a carriage of the seat map with the layout, fare
+// multiplier and amenities of its type
+type SeatMapCarriage struct {
+	ID             int64         `json:"id"`
+	Number         int           `json:"number"`
+	Type           string        `json:"type"`
+	Layout         layout.Layout `json:"layout"`
+	FareMultiplier float64       `json:"fareMultiplier"`
+	Amenities      []string      `json:"amenities"`
+	Seats          []SeatMapSeat `json:"seats"`
+}
+
+// SeatMapSeat is a seat with its attributes and its status on the date
+type SeatMapSeat struct {
+	models.Seat
+	Status string `json:"status"`
+}
+
 type PaymentRequest struct {
 	CardNumber string `json:"cardNumber" binding:"required"`
 	ExpiryDate string `json:"expiryDate" binding:"required"`
@@ -84,14 +266,190 @@ type PaymentResponse struct {
 	TransactionID string `json:"transactionId"`
 }
 
+// RefundRequest lists the tickets to refund, each once; an empty list
+// refunds every active ticket of the order
+type RefundRequest struct {
+	TicketIDs []int64 `json:"ticketIds"`
+}
+
+type RefundResponse struct {
+	Status         string  `json:"status"`
+	RefundedAmount float64 `json:"refundedAmount"`
+}
+
 type CreateRouteRequest struct {
 	Name    string `json:"name" binding:"required"`
 	TrainID int64  `json:"trainId" binding:"required"`
+	// Price is the adult fare in the base class for the whole route
+	Price float64 `json:"price" binding:"required,gt=0"`
+	// CalendarID is the service calendar; without one the route runs daily
+	CalendarID *int64 `json:"calendarId"`
 }
 
 type UpdateRouteRequest struct {
-	Name    string `json:"name"`
-	TrainID int64  `json:"trainId"`
+	Name    string   `json:"name"`
+	TrainID int64    `json:"trainId"`
+	Price   *float64 `json:"price" binding:"omitempty,gt=0"`
+	// CalendarID replaces the service calendar; 0 makes the route run daily
+	CalendarID *int64 `json:"calendarId"`
+}
+
+// CreateCarriageRequest adds a carriage to a train. Its seats are generated
+// from the layout of its type.
+type CreateCarriageRequest struct {
+	Number int   `json:"number" binding:"required,min=1"`
+	TypeID int64 `json:"typeId" binding:"required"`
+}
+
+// UpdateCarriageRequest renumbers a carriage or changes its type, which
+// regenerates its seats. Empty fields are left unchanged.
+type UpdateCarriageRequest struct {
+	Number int   `json:"number" binding:"min=0"`
+	TypeID int64 `json:"typeId"`
+}
+
+type CarriageResponse struct {
+	ID      int64               `json:"id"`
+	TrainID int64               `json:"trainId"`
+	Number  int                 `json:"number"`
+	Type    models.CarriageType `json:"type"`
+	Seats   []models.Seat       `json:"seats"`
+}
+
+// CreateCarriageTypeRequest describes a carriage type. Seats are numbered
+// compartment by compartment, then the side berths, then open seats.
+type CreateCarriageTypeRequest struct {
+	Name                string   `json:"name" binding:"required"`
+	Compartments        int      `json:"compartments" binding:"min=0"`
+	SeatsPerCompartment int      `json:"seatsPerCompartment" binding:"min=0"`
+	SideSeats           int      `json:"sideSeats" binding:"min=0"`
+	OpenSeats           int      `json:"openSeats" binding:"min=0"`
+	FareMultiplier      float64  `json:"fareMultiplier" binding:"required,gt=0"`
+	Amenities           []string `json:"amenities"`
+}
+
+// UpdateCarriageTypeRequest changes a carriage type. Omitted fields are left
+// unchanged; the layout of a type carriages are built from can't change.
+type UpdateCarriageTypeRequest struct {
+	Name                string   `json:"name"`
+	Compartments        *int     `json:"compartments" binding:"omitempty,min=0"`
+	SeatsPerCompartment *int     `json:"seatsPerCompartment" binding:"omitempty,min=0"`
+	SideSeats           *int     `json:"sideSeats" binding:"omitempty,min=0"`
+	OpenSeats           *int     `json:"openSeats" binding:"omitempty,min=0"`
+	FareMultiplier      *float64 `json:"fareMultiplier" binding:"omitempty,gt=0"`
+	Amenities           []string `json:"amenities"`
+}
+
+// CarriageTypeResponse is a carriage type with the number of seats its
+// layout has
+type CarriageTypeResponse struct {
+	models.CarriageType
+	Capacity int `json:"capacity"`
+}
+
+type CreateStationRequest struct {
+	Name string `json:"name" binding:"required"`
+	City string `json:"city" binding:"required"`
+	// TimeZone is an IANA zone such as Europe/Minsk, the default
+	TimeZone string `json:"timeZone"`
+}
+
+type UpdateStationRequest struct {
+	Name     string `json:"name"`
+	City     string `json:"city"`
+	TimeZone string `json:"timeZone"`
+}
+
+// StopRequest is a call of a route at a station. Times are local times at
+// the station in HH:MM format; the day offsets count the days since the train
+// left its first station.
+type StopRequest struct {
+	StationID int64 `json:"stationId" binding:"required"`
+	// StopOrder is the position of the stop, from 1. When adding a stop, 0
+	// appends it and later stops move down to make room.
+	StopOrder          int     `json:"stopOrder"`
+	ArrivalTime        *string `json:"arrivalTime"`
+	DepartureTime      *string `json:"departureTime"`
+	ArrivalDayOffset   int     `json:"arrivalDayOffset" binding:"min=0"`
+	DepartureDayOffset int     `json:"departureDayOffset" binding:"min=0"`
+}
+
+// UpdateStopRequest retimes a stop and, with a StopOrder, moves it
+type UpdateStopRequest struct {
+	StopOrder          int     `json:"stopOrder"`
+	ArrivalTime        *string `json:"arrivalTime"`
+	DepartureTime      *string `json:"departureTime"`
+	ArrivalDayOffset   int     `json:"arrivalDayOffset" binding:"min=0"`
+	DepartureDayOffset int     `json:"departureDayOffset" binding:"min=0"`
+}
+
+// ReplaceStopsRequest is the complete timetable of a route
+type ReplaceStopsRequest struct {
+	Stops []StopRequest `json:"stops" binding:"required,dive"`
+}
+
+type StopResponse struct {
+	StationID          int64  `json:"stationId"`
+	Station            string `json:"station"`
+	City               string `json:"city"`
+	TimeZone           string `json:"timeZone"`
+	StopOrder          int    `json:"stopOrder"`
+	ArrivalTime        string `json:"arrivalTime,omitempty"`
+	DepartureTime      string `json:"departureTime,omitempty"`
+	ArrivalDayOffset   int    `json:"arrivalDayOffset"`
+	DepartureDayOffset int    `json:"departureDayOffset"`
+}
+
+// CalendarRequest creates or replaces a service calendar. Dates are in
+// YYYY-MM-DD format.
+type CalendarRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	Monday       bool    `json:"monday"`
+	Tuesday      bool    `json:"tuesday"`
+	Wednesday    bool    `json:"wednesday"`
+	Thursday     bool    `json:"thursday"`
+	Friday       bool    `json:"friday"`
+	Saturday     bool    `json:"saturday"`
+	Sunday       bool    `json:"sunday"`
+	StartDate    *string `json:"startDate"`
+	EndDate      *string `json:"endDate"`
+	SkipHolidays bool    `json:"skipHolidays"`
+}
+
+type CalendarResponse struct {
+	ID           int64               `json:"id"`
+	Name         string              `json:"name"`
+	Monday       bool                `json:"monday"`
+	Tuesday      bool                `json:"tuesday"`
+	Wednesday    bool                `json:"wednesday"`
+	Thursday     bool                `json:"thursday"`
+	Friday       bool                `json:"friday"`
+	Saturday     bool                `json:"saturday"`
+	Sunday       bool                `json:"sunday"`
+	StartDate    string              `json:"startDate,omitempty"`
+	EndDate      string              `json:"endDate,omitempty"`
+	SkipHolidays bool                `json:"skipHolidays"`
+	Exceptions   []ExceptionResponse `json:"exceptions"`
+}
+
+// ExceptionRequest adds (ADDED) or cancels (REMOVED) service on a date
+type ExceptionRequest struct {
+	Type string `json:"type" binding:"required"`
+}
+
+type ExceptionResponse struct {
+	Date string `json:"date"`
+	Type string `json:"type"`
+}
+
+type HolidayRequest struct {
+	Date string `json:"date" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+type HolidayResponse struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
 }
 
 type CreateTrainRequest struct {
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+// httpError carries an HTTP status out of code that runs inside a
+// transaction, where the handler can't write the response directly
+type httpError struct {
+	status  int
+	message string
+	// fields are added to the JSON body next to the error message
+	fields gin.H
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func newHTTPError(status int, message string) error {
+	return &httpError{status: status, message: message}
+}
+
+func newHTTPErrorWith(status int, message string, fields gin.H) error {
+	return &httpError{status: status, message: message, fields: fields}
+}
+
+// respondError writes err as a JSON error. Rejected order status changes are
+// reported as 409; other errors without an HTTP status are reported as 500
+// with fallback as the message.
+func respondError(c *gin.Context, err error, fallback string) {
+	var he *httpError
+	if errors.As(err, &he) {
+		body := gin.H{"error": he.message}
+		for k, v := range he.fields {
+			body[k] = v
+		}
+		c.JSON(he.status, body)
+		return
+	}
+	var te *models.TransitionError
+	if errors.As(err, &te) {
+		c.JSON(http.StatusConflict, gin.H{"error": te.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
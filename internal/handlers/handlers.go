@@ -1,26 +1,46 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/config"
+	"github.com/project13/backend-stealthisproject/internal/journey"
+	"github.com/project13/backend-stealthisproject/internal/layout"
+	"github.com/project13/backend-stealthisproject/internal/mail"
 	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/pricing"
 	"github.com/project13/backend-stealthisproject/internal/repository"
+	"github.com/project13/backend-stealthisproject/internal/schedule"
 	"github.com/project13/backend-stealthisproject/pkg/auth"
 )
 
 type Handlers struct {
 	repos       *repository.Repositories
 	authService *auth.AuthService
+	revocations *auth.RevocationList
+	mailer      mail.Mailer
+	cfg         *config.Config
+
+	// deliveries tracks emails still being sent in the background
+	deliveries sync.WaitGroup
 }
 
-func NewHandlers(repos *repository.Repositories, authService *auth.AuthService) *Handlers {
+func NewHandlers(repos *repository.Repositories, authService *auth.AuthService, revocations *auth.RevocationList, mailer mail.Mailer, cfg *config.Config) *Handlers {
 	return &Handlers{
 		repos:       repos,
 		authService: authService,
+		revocations: revocations,
+		mailer:      mailer,
+		cfg:         cfg,
 	}
 }
 
@@ -35,6 +55,7 @@ func NewHandlers(repos *repository.Repositories, authService *auth.AuthService)
 // @Failure 400 {object} map[string]string
 // @Router /auth/register [post]
 func (h *Handlers) Register(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -42,7 +63,7 @@ func (h *Handlers) Register(c *gin.Context) {
 	}
 
 	// Check if user exists
-	existingUser, _ := h.repos.User.GetByEmail(req.Email)
+	existingUser, _ := h.repos.User.GetByEmail(ctx, req.Email)
 	if existingUser != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User with this email already exists"})
 		return
@@ -59,9 +80,9 @@ func (h *Handlers) Register(c *gin.Context) {
 	user := &models.User{
 		Email:        req.Email,
 		PasswordHash: passwordHash,
-		Role:         "PASSENGER",
+		Roles:        []string{},
 	}
-	if err := h.repos.User.Create(user); err != nil {
+	if err := h.repos.User.Create(ctx, user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
@@ -71,49 +92,53 @@ func (h *Handlers) Register(c *gin.Context) {
 		UserID:    user.ID,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		IsPrimary: true,
 	}
-	if err := h.repos.Passenger.Create(passenger); err != nil {
+	if err := h.repos.Passenger.Create(ctx, passenger); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create passenger profile"})
 		return
 	}
 
-	// Generate token
-	token, err := h.authService.GenerateToken(user.ID, user.Role)
+	// Generate tokens
+	response, err := h.startSession(c, user, req.Device)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, AuthResponse{
-		Token: token,
-		User: &UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			FirstName: req.FirstName,
-			LastName:  req.LastName,
-			Role:      user.Role,
-		},
-	})
+	if err := h.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	response.User = &UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Roles:     user.Roles,
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // Login handles user login
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return a short-lived JWT access token with a refresh token
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login credentials"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} AuthResponse
 // @Failure 401 {object} map[string]string
 // @Router /auth/login [post]
 func (h *Handlers) Login(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, err := h.repos.User.GetByEmail(req.Email)
+	user, err := h.repos.User.GetByEmail(ctx, req.Email)
 	if err != nil || user == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
@@ -124,13 +149,13 @@ func (h *Handlers) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.GenerateToken(user.ID, user.Role)
+	response, err := h.startSession(c, user, req.Device)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, response)
 }
 
 // GetCurrentUser returns current user profile
@@ -143,20 +168,22 @@ func (h *Handlers) Login(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Router /users/me [get]
 func (h *Handlers) GetCurrentUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
-	user, err := h.repos.User.GetByID(id)
+	user, err := h.repos.User.GetByID(ctx, id)
 	if err != nil || user == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	passenger, _ := h.repos.Passenger.GetByUserID(id)
+	passenger, _ := h.repos.Passenger.GetByUserID(ctx, id)
 	response := UserResponse{
-		ID:    user.ID,
-		Email: user.Email,
-		Role:  user.Role,
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Roles:         user.Roles,
 	}
 	if passenger != nil {
 		response.FirstName = passenger.FirstName
@@ -179,6 +206,7 @@ func (h *Handlers) GetCurrentUser(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Router /users/me [put]
 func (h *Handlers) UpdateCurrentUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
@@ -188,7 +216,7 @@ func (h *Handlers) UpdateCurrentUser(c *gin.Context) {
 		return
 	}
 
-	passenger, err := h.repos.Passenger.GetByUserID(id)
+	passenger, err := h.repos.Passenger.GetByUserID(ctx, id)
 	if err != nil || passenger == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Passenger profile not found"})
 		return
@@ -203,19 +231,20 @@ func (h *Handlers) UpdateCurrentUser(c *gin.Context) {
 	// Allow empty string to clear passport data
 	passenger.PassportData = req.PassportData
 
-	if err := h.repos.Passenger.Update(passenger); err != nil {
+	if err := h.repos.Passenger.Update(ctx, passenger); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 		return
 	}
 
-	user, _ := h.repos.User.GetByID(id)
+	user, _ := h.repos.User.GetByID(ctx, id)
 	response := UserResponse{
-		ID:           user.ID,
-		Email:        user.Email,
-		FirstName:    passenger.FirstName,
-		LastName:     passenger.LastName,
-		PassportData: passenger.PassportData,
-		Role:         user.Role,
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		FirstName:     passenger.FirstName,
+		LastName:      passenger.LastName,
+		PassportData:  passenger.PassportData,
+		Roles:         user.Roles,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -229,10 +258,16 @@ func (h *Handlers) UpdateCurrentUser(c *gin.Context) {
 // @Produce json
 // @Param from_city query string true "Departure city"
 // @Param to_city query string true "Arrival city"
-// @Param date query string true "Travel date"
+// @Param date query string true "Travel date (YYYY-MM-DD)"
+// @Param position query string false "Comma-separated seat positions: LOWER, UPPER, SIDE_LOWER, SIDE_UPPER"
+// @Param placement query string false "Seat placement: WINDOW or AISLE"
+// @Param direction query string false "Direction the seat faces: FORWARD or BACKWARD"
+// @Param wheelchair_space query bool false "Only seats with wheelchair space"
+// @Param reduced_mobility query bool false "Only seats for passengers with reduced mobility"
 // @Success 200 {array} RouteSearchResponse
 // @Router /routes/search [get]
 func (h *Handlers) SearchRoutes(c *gin.Context) {
+	ctx := c.Request.Context()
 	fromCity := c.Query("from_city")
 	toCity := c.Query("to_city")
 	date := c.Query("date")
@@ -241,8 +276,27 @@ func (h *Handlers) SearchRoutes(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "from_city, to_city, and date are required"})
 		return
 	}
+	travelDate, err := schedule.ParseDate(date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	zone, err := h.cityTimeZone(ctx, fromCity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stations"})
+		return
+	}
+	if err := schedule.ValidateTravelDate(travelDate, time.Now(), zone, h.cfg.BookingHorizonDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, filtered, err := parseSeatFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	routes, err := h.repos.Route.Search(fromCity, toCity, date)
+	routes, err := h.repos.Route.Search(ctx, fromCity, toCity, date)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to search routes: %v", err)})
 		return
@@ -250,35 +304,67 @@ func (h *Handlers) SearchRoutes(c *gin.Context) {
 
 	var responses []RouteSearchResponse
 	for _, route := range routes {
-		train, _ := h.repos.Train.GetByID(route.TrainID)
-		routeStations, _ := h.repos.Route.GetStations(route.ID)
+		runs, err := h.routeRunsOn(ctx, &route, travelDate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check service calendar"})
+			return
+		}
+		if !runs {
+			continue
+		}
+
+		train, _ := h.repos.Train.GetByID(ctx, route.TrainID)
+		routeStations, err := h.repos.Route.GetStations(ctx, route.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get route stops"})
+			return
+		}
 
+		from, to := segmentStops(routeStations, fromCity, toCity)
+		if from < 0 || to < 0 {
+			continue
+		}
+		segment := segmentOf(routeStations, from, to)
+
+		// Report times of the searched segment for the run leaving on the
+		// requested date; overnight trains arrive on a later day
 		var departureTime, arrivalTime string
-		if len(routeStations) > 0 {
-			if routeStations[0].DepartureTime != nil {
-				// Format time as ISO 8601 with date (use today's date + time from DB)
-				depTime := *routeStations[0].DepartureTime
-				// Combine today's date with the time from database
-				today := time.Now()
-				combinedTime := time.Date(today.Year(), today.Month(), today.Day(),
-					depTime.Hour(), depTime.Minute(), depTime.Second(), depTime.Nanosecond(), time.UTC)
-				departureTime = combinedTime.Format(time.RFC3339)
-			}
-			if len(routeStations) > 1 && routeStations[len(routeStations)-1].ArrivalTime != nil {
-				arrTime := *routeStations[len(routeStations)-1].ArrivalTime
-				today := time.Now()
-				combinedTime := time.Date(today.Year(), today.Month(), today.Day(),
-					arrTime.Hour(), arrTime.Minute(), arrTime.Second(), arrTime.Nanosecond(), time.UTC)
-				arrivalTime = combinedTime.Format(time.RFC3339)
-			}
+		var durationMinutes int
+		departure := schedule.Departure(travelDate, routeStations[from])
+		arrival := schedule.Arrival(travelDate, routeStations[to])
+		if !departure.IsZero() {
+			departureTime = departure.Format(time.RFC3339)
+		}
+		if !arrival.IsZero() {
+			arrivalTime = arrival.Format(time.RFC3339)
+		}
+		if !departure.IsZero() && !arrival.IsZero() {
+			durationMinutes = int(arrival.Sub(departure).Minutes())
+		}
+
+		// Lowest adult fare for the segment, in the base carriage class
+		price, err := pricing.Calculate(pricing.Fare{
+			RoutePrice: route.Price,
+			Legs:       to - from,
+			TotalLegs:  len(routeStations) - 1,
+		})
+		if errors.Is(err, pricing.ErrNoPrice) {
+			// Not on sale until an admin sets its fare
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate fare"})
+			return
 		}
 
-		// Calculate available seats (simplified)
-		carriages, _ := h.repos.Carriage.GetByTrainID(route.TrainID)
-		availableSeats := 0
-		for _, carriage := range carriages {
-			seats, _ := h.repos.Seat.GetByCarriageID(carriage.ID)
-			availableSeats += len(seats)
+		availableSeats, err := h.repos.Seat.CountAvailable(ctx, route.ID, date, segment, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count available seats"})
+			return
+		}
+		// With a seat filter, only trains that have a matching seat are of interest
+		if filtered && availableSeats == 0 {
+			continue
 		}
 
 		trainNumber := ""
@@ -287,12 +373,15 @@ func (h *Handlers) SearchRoutes(c *gin.Context) {
 		}
 
 		responses = append(responses, RouteSearchResponse{
-			RouteID:       route.ID,
-			TrainNumber:   trainNumber,
-			DepartureTime: departureTime,
-			ArrivalTime:   arrivalTime,
-			Price:         route.Price, // Use actual route price from database
-			AvailableSeats: availableSeats,
+			RouteID:         route.ID,
+			TrainNumber:     trainNumber,
+			FromStationID:   routeStations[from].StationID,
+			ToStationID:     routeStations[to].StationID,
+			DepartureTime:   departureTime,
+			ArrivalTime:     arrivalTime,
+			DurationMinutes: durationMinutes,
+			Price:           price,
+			AvailableSeats:  availableSeats,
 		})
 	}
 
@@ -303,38 +392,316 @@ func (h *Handlers) SearchRoutes(c *gin.Context) {
 	c.JSON(http.StatusOK, responses)
 }
 
+// parseSeatFilter reads the seat attribute filters from the query string.
+// filtered is false when none were given.
+func parseSeatFilter(c *gin.Context) (filter models.SeatFilter, filtered bool, err error) {
+	if v := c.Query("position"); v != "" {
+		for _, p := range strings.Split(v, ",") {
+			position := models.SeatPosition(strings.ToUpper(strings.TrimSpace(p)))
+			if !models.ValidSeatPosition(position) {
+				return filter, false, fmt.Errorf("unknown seat position %q", p)
+			}
+			filter.Positions = append(filter.Positions, position)
+		}
+	}
+	if v := c.Query("placement"); v != "" {
+		filter.Placement = models.SeatPlacement(strings.ToUpper(v))
+		if !models.ValidSeatPlacement(filter.Placement) {
+			return filter, false, fmt.Errorf("unknown seat placement %q", v)
+		}
+	}
+	if v := c.Query("direction"); v != "" {
+		filter.Direction = models.TravelDirection(strings.ToUpper(v))
+		if !models.ValidTravelDirection(filter.Direction) {
+			return filter, false, fmt.Errorf("unknown direction %q", v)
+		}
+	}
+	if v := c.Query("wheelchair_space"); v != "" {
+		if filter.WheelchairSpace, err = strconv.ParseBool(v); err != nil {
+			return filter, false, fmt.Errorf("wheelchair_space must be true or false")
+		}
+	}
+	if v := c.Query("reduced_mobility"); v != "" {
+		if filter.ReducedMobility, err = strconv.ParseBool(v); err != nil {
+			return filter, false, fmt.Errorf("reduced_mobility must be true or false")
+		}
+	}
+
+	filtered = len(filter.Positions) > 0 || filter.Placement != "" || filter.Direction != "" ||
+		filter.WheelchairSpace || filter.ReducedMobility
+	return filter, filtered, nil
+}
+
+// cityTimeZone returns the time zone of the stations in city, or "" (UTC)
+// when the city has none
+func (h *Handlers) cityTimeZone(ctx context.Context, city string) (string, error) {
+	stations, err := h.repos.Station.GetByCity(ctx, city)
+	if err != nil || len(stations) == 0 {
+		return "", err
+	}
+	return stations[0].TimeZone, nil
+}
+
+// segmentStops returns the indexes of the boarding stop in fromCity and the
+// first following stop in toCity, or -1 when either is missing
+func segmentStops(stops []models.RouteStation, fromCity, toCity string) (int, int) {
+	from := -1
+	for i := range stops {
+		if from < 0 {
+			if stops[i].City == fromCity {
+				from = i
+			}
+			continue
+		}
+		if stops[i].City == toCity {
+			return from, i
+		}
+	}
+	return from, -1
+}
+
+// maxJourneys caps the number of itineraries the journey planner returns
+const maxJourneys = 10
+
+// SearchJourneys plans journeys with transfers
+// @Summary Search journeys
+// @Description Find itineraries between cities on a date, changing trains up to max_transfers times. Itineraries are ordered by arrival time, duration and price.
+// @Tags Routes
+// @Produce json
+// @Param from_city query string true "Departure city"
+// @Param to_city query string true "Arrival city"
+// @Param date query string true "Travel date (YYYY-MM-DD)"
+// @Param max_transfers query int false "Most train changes allowed"
+// @Success 200 {array} JourneyResponse
+// @Failure 400 {object} map[string]string
+// @Router /routes/journeys [get]
+func (h *Handlers) SearchJourneys(c *gin.Context) {
+	ctx := c.Request.Context()
+	fromCity := c.Query("from_city")
+	toCity := c.Query("to_city")
+	date := c.Query("date")
+
+	if fromCity == "" || toCity == "" || date == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_city, to_city, and date are required"})
+		return
+	}
+	if fromCity == toCity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_city and to_city must differ"})
+		return
+	}
+	travelDate, err := schedule.ParseDate(date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	zone, err := h.cityTimeZone(ctx, fromCity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stations"})
+		return
+	}
+	if err := schedule.ValidateTravelDate(travelDate, time.Now(), zone, h.cfg.BookingHorizonDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	maxTransfers := h.cfg.MaxTransfers
+	if v := c.Query("max_transfers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > h.cfg.MaxTransfers {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("max_transfers must be between 0 and %d", h.cfg.MaxTransfers)})
+			return
+		}
+		maxTransfers = n
+	}
+
+	stations, err := h.repos.Station.GetAll(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journeys"})
+		return
+	}
+	stationByID := make(map[int64]models.Station, len(stations))
+	var origins, destinations []int64
+	for _, station := range stations {
+		stationByID[station.ID] = station
+		switch station.City {
+		case fromCity:
+			origins = append(origins, station.ID)
+		case toCity:
+			destinations = append(destinations, station.ID)
+		}
+	}
+
+	routes, err := h.repos.Route.GetAll(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journeys"})
+		return
+	}
+	stopsByRoute, err := h.repos.Route.GetAllStations(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journeys"})
+		return
+	}
+	runsOn, err := h.serviceDay(ctx, travelDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journeys"})
+		return
+	}
+	routeByID := make(map[int64]models.Route, len(routes))
+	trips := make([]journey.Trip, 0, len(routes))
+	for _, route := range routes {
+		routeByID[route.ID] = route
+		// Routes without a fare aren't on sale
+		if !runsOn(&route) || route.Price <= 0 {
+			continue
+		}
+		if stops := stopsByRoute[route.ID]; len(stops) >= 2 {
+			trips = append(trips, journey.Trip{RouteID: route.ID, Price: route.Price, Stops: journey.Timetable(travelDate, stops)})
+		}
+	}
+
+	// Trains that have already left can't be caught. The trips are those
+	// leaving on the travel date in local time, which may be the day before
+	// in UTC, so there is no other lower bound.
+	itineraries := journey.Plan(trips, origins, destinations, time.Now(), journey.Options{
+		MaxTransfers:    maxTransfers,
+		MinTransferTime: h.cfg.MinTransferTime,
+		Limit:           maxJourneys,
+	})
+
+	responses := []JourneyResponse{}
+	for _, it := range itineraries {
+		response := JourneyResponse{
+			DepartureTime:   it.Departure().Format(time.RFC3339),
+			ArrivalTime:     it.Arrival().Format(time.RFC3339),
+			DurationMinutes: int(it.Duration().Minutes()),
+			Transfers:       it.Transfers(),
+			Price:           it.Price(),
+		}
+		for _, leg := range it.Legs {
+			from, to := leg.Trip.Stops[leg.From], leg.Trip.Stops[leg.To]
+			legResponse := JourneyLegResponse{
+				RouteID:       leg.Trip.RouteID,
+				FromStationID: from.StationID,
+				FromStation:   stationByID[from.StationID].Name,
+				ToStationID:   to.StationID,
+				ToStation:     stationByID[to.StationID].Name,
+				DepartureTime: leg.Departure().Format(time.RFC3339),
+				ArrivalTime:   leg.Arrival().Format(time.RFC3339),
+				Price:         leg.Price,
+			}
+			train, _ := h.repos.Train.GetByID(ctx, routeByID[leg.Trip.RouteID].TrainID)
+			if train != nil {
+				legResponse.TrainNumber = train.Number
+			}
+			segment := models.Segment{FromStop: from.StopOrder, ToStop: to.StopOrder}
+			legResponse.AvailableSeats, err = h.repos.Seat.CountAvailable(ctx, leg.Trip.RouteID, date, segment, models.SeatFilter{})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count available seats"})
+				return
+			}
+			response.Legs = append(response.Legs, legResponse)
+		}
+		responses = append(responses, response)
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// routeSegment returns the indexes in stops of the boarding and alighting
+// stations. Nil station IDs default to the first and the last stop.
+func routeSegment(stops []models.RouteStation, fromStationID, toStationID *int64) (int, int, error) {
+	from, to := 0, len(stops)-1
+	if fromStationID != nil {
+		from = -1
+		for i := range stops {
+			if stops[i].StationID == *fromStationID {
+				from = i
+				break
+			}
+		}
+		if from < 0 {
+			return 0, 0, errors.New("boarding station is not on this route")
+		}
+	}
+	if toStationID != nil {
+		to = -1
+		for i := range stops {
+			if stops[i].StationID == *toStationID {
+				to = i
+				break
+			}
+		}
+		if to < 0 {
+			return 0, 0, errors.New("alighting station is not on this route")
+		}
+	}
+	if from >= to {
+		return 0, 0, errors.New("alighting station must come after the boarding station")
+	}
+	return from, to, nil
+}
+
+// segmentOf returns the segment between the stops at indexes from and to
+func segmentOf(stops []models.RouteStation, from, to int) models.Segment {
+	return models.Segment{FromStop: stops[from].StopOrder, ToStop: stops[to].StopOrder}
+}
+
+// queryInt64 reads an optional integer query parameter
+func queryInt64(c *gin.Context, name string) (*int64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", name)
+	}
+	return &n, nil
+}
+
 // GetRoute gets route details
 // @Summary Get route details
 // @Description Get detailed information about a route
 // @Tags Routes
 // @Produce json
 // @Param id path int true "Route ID"
+// @Param date query string false "Departure date of the run whose times to show (YYYY-MM-DD), today by default"
 // @Success 200 {object} map[string]interface{}
 // @Router /routes/{id} [get]
 func (h *Handlers) GetRoute(c *gin.Context) {
+	ctx := c.Request.Context()
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
 		return
 	}
+	now := time.Now().UTC()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := c.Query("date"); v != "" {
+		if date, err = schedule.ParseDate(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	route, err := h.repos.Route.GetByID(id)
+	route, err := h.repos.Route.GetByID(ctx, id)
 	if err != nil || route == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
 		return
 	}
 
-	train, _ := h.repos.Train.GetByID(route.TrainID)
-	routeStations, _ := h.repos.Route.GetStations(route.ID)
+	train, _ := h.repos.Train.GetByID(ctx, route.TrainID)
+	routeStations, _ := h.repos.Route.GetStations(ctx, route.ID)
 
 	var stations []map[string]interface{}
 	for _, rs := range routeStations {
-		station, _ := h.repos.Station.GetByID(rs.StationID)
+		station, _ := h.repos.Station.GetByID(ctx, rs.StationID)
 		stations = append(stations, map[string]interface{}{
-			"station":       station,
-			"arrivalTime":   rs.ArrivalTime,
-			"departureTime": rs.DepartureTime,
-			"stopOrder":     rs.StopOrder,
+			"station":            station,
+			"arrivalTime":        formatLocal(schedule.Arrival(date, rs), rs.TimeZone),
+			"departureTime":      formatLocal(schedule.Departure(date, rs), rs.TimeZone),
+			"arrivalDayOffset":   rs.ArrivalDayOffset,
+			"departureDayOffset": rs.DepartureDayOffset,
+			"stopOrder":          rs.StopOrder,
 		})
 	}
 
@@ -346,9 +713,153 @@ func (h *Handlers) GetRoute(c *gin.Context) {
 	})
 }
 
+// GetSeatMap gets the seat map of a route
+// @Summary Get seat map
+// @Description Get every carriage of the route's train with its seats and whether each is free, held or sold on the given date
+// @Tags Routes
+// @Produce json
+// @Param id path int true "Route ID"
+// @Param date query string true "Departure date (YYYY-MM-DD)"
+// @Param from_station_id query int false "Boarding station, the first stop by default"
+// @Param to_station_id query int false "Alighting station, the last stop by default"
+// @Param position query string false "Comma-separated seat positions: LOWER, UPPER, SIDE_LOWER, SIDE_UPPER"
+// @Param placement query string false "Seat placement: WINDOW or AISLE"
+// @Param direction query string false "Direction the seat faces: FORWARD or BACKWARD"
+// @Param wheelchair_space query bool false "Only seats with wheelchair space"
+// @Param reduced_mobility query bool false "Only seats for passengers with reduced mobility"
+// @Success 200 {object} SeatMapResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /routes/{id}/seatmap [get]
+func (h *Handlers) GetSeatMap(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+		return
+	}
+
+	date := c.Query("date")
+	if date == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date is required"})
+		return
+	}
+	travelDate, err := schedule.ParseDate(date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, _, err := parseSeatFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fromStationID, err := queryInt64(c, "from_station_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	toStationID, err := queryInt64(c, "to_station_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := h.repos.Route.GetByID(ctx, id)
+	if err != nil || route == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	runs, err := h.routeRunsOn(ctx, route, travelDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check service calendar"})
+		return
+	}
+	if !runs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": schedule.ErrNotRunning.Error()})
+		return
+	}
+
+	routeStations, err := h.repos.Route.GetStations(ctx, route.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get route stops"})
+		return
+	}
+	if len(routeStations) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route has no timetable"})
+		return
+	}
+	if err := schedule.ValidateTravelDate(travelDate, time.Now(), routeStations[0].TimeZone, h.cfg.BookingHorizonDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, err := routeSegment(routeStations, fromStationID, toStationID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := SeatMapResponse{
+		RouteID:       route.ID,
+		DepartureDate: date,
+		FromStationID: routeStations[from].StationID,
+		ToStationID:   routeStations[to].StationID,
+		Carriages:     []SeatMapCarriage{},
+	}
+	train, _ := h.repos.Train.GetByID(ctx, route.TrainID)
+	if train != nil {
+		response.TrainNumber = train.Number
+	}
+
+	occupied, err := h.repos.Seat.GetOccupied(ctx, route.ID, date, segmentOf(routeStations, from, to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get seat map"})
+		return
+	}
+	carriages, err := h.repos.Carriage.GetByTrainID(ctx, route.TrainID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get seat map"})
+		return
+	}
+
+	for _, carriage := range carriages {
+		seats, err := h.repos.Seat.GetByCarriageID(ctx, carriage.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get seat map"})
+			return
+		}
+
+		item := SeatMapCarriage{
+			ID:             carriage.ID,
+			Number:         carriage.Number,
+			Type:           carriage.Type.Name,
+			Layout:         layout.ForType(carriage.Type),
+			FareMultiplier: carriage.Type.FareMultiplier,
+			Amenities:      carriage.Type.Amenities,
+			Seats:          make([]SeatMapSeat, 0, len(seats)),
+		}
+		for _, seat := range seats {
+			if !filter.Matches(&seat) {
+				continue
+			}
+			status := SeatFree
+			switch occupied[seat.ID] {
+			case models.TicketHeld:
+				status = SeatHeld
+			case models.TicketActive:
+				status = SeatSold
+			}
+			item.Seats = append(item.Seats, SeatMapSeat{Seat: seat, Status: status})
+		}
+		response.Carriages = append(response.Carriages, item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // CreateOrder creates a new order
 // @Summary Create order
-// @Description Create a new ticket order
+// @Description Create a ticket order for one or more seats and passengers, on a single route or on every leg of a journey with transfers. All tickets are issued atomically. Staff with orders:write can book for another user with userId.
 // @Tags Orders
 // @Security BearerAuth
 // @Accept json
@@ -356,8 +867,10 @@ func (h *Handlers) GetRoute(c *gin.Context) {
 // @Param request body CreateOrderRequest true "Order data"
 // @Success 201 {object} OrderResponse
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /orders [post]
 func (h *Handlers) CreateOrder(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
@@ -367,118 +880,293 @@ func (h *Handlers) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Check seat availability
-	seat, err := h.repos.Seat.GetByID(req.SeatID)
-	if err != nil || seat == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Seat not found"})
-		return
+	// Staff at the counter book for the passenger's account
+	owner := id
+	if req.UserID != nil && *req.UserID != id {
+		if !auth.HasPermission(c.GetStringSlice("permissions"), models.PermOrdersWrite) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		user, err := h.repos.User.GetByID(ctx, *req.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+			return
+		}
+		owner = user.ID
 	}
 
-	// Get route to find train
-	route, err := h.repos.Route.GetByID(req.RouteID)
-	if err != nil || route == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Route not found"})
+	legs := req.Legs
+	if len(legs) == 0 {
+		if req.RouteID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "routeId or legs is required"})
+			return
+		}
+		items := req.Items
+		if len(items) == 0 {
+			if req.SeatID == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item is required"})
+				return
+			}
+			// Single-seat form used by older clients
+			items = []OrderItemRequest{{
+				SeatID:            req.SeatID,
+				PassengerID:       req.PassengerID,
+				PassengerCategory: req.PassengerCategory,
+				ExpectedPrice:     req.ExpectedPrice,
+			}}
+		}
+		legs = []OrderLegRequest{{
+			RouteID:       req.RouteID,
+			FromStationID: req.FromStationID,
+			ToStationID:   req.ToStationID,
+			Items:         items,
+		}}
+	}
+
+	departureDate, err := schedule.ParseDate(req.DepartureDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Create order with price from request
-	routeID := route.ID
-	order := &models.Order{
-		UserID:     id,
-		RouteID:    &routeID,
-		Status:     "PENDING",
-		TotalAmount: req.Price,
+	var drafts []ticketDraft
+	var total float64
+	var previous *orderLeg
+	for i := range legs {
+		leg, err := h.prepareLeg(ctx, owner, &legs[i], departureDate)
+		if err != nil {
+			respondError(c, err, "Failed to create order")
+			return
+		}
+		// Consecutive legs must connect at a station with time to change
+		if previous != nil {
+			if previous.arrivalStationID() != leg.departureStationID() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Leg %d does not start where leg %d ends", i+1, i)})
+				return
+			}
+			if leg.departure().Before(previous.arrival().Add(h.cfg.MinTransferTime)) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Not enough time to change trains before leg %d", i+1)})
+				return
+			}
+		}
+		previous = leg
+
+		drafts = append(drafts, leg.drafts...)
+		for _, draft := range leg.drafts {
+			total += draft.ticket.Price
+		}
 	}
-	if err := h.repos.Order.Create(order); err != nil {
+
+	expiresAt := time.Now().Add(h.cfg.HoldTTL)
+	order := &models.Order{
+		UserID:      owner,
+		Status:      models.OrderPending,
+		TotalAmount: pricing.Round(total),
+		ExpiresAt:   &expiresAt,
+	}
+	// Orders for a single route keep pointing at it
+	if len(legs) == 1 {
+		order.RouteID = &legs[0].RouteID
+	}
+	// The order, new passengers and all tickets are created atomically; if
+	// any seat is taken in the meantime nothing is booked
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		if err := tx.Order.Create(ctx, order, id); err != nil {
+			return err
+		}
+		for i, draft := range drafts {
+			if draft.passenger != nil {
+				if err := tx.Passenger.Create(ctx, draft.passenger); err != nil {
+					return err
+				}
+				draft.ticket.PassengerID = &draft.passenger.ID
+			}
+			draft.ticket.OrderID = order.ID
+			draft.ticket.TicketNumber = fmt.Sprintf("TK-%d-%d", order.ID, i+1)
+			if err := tx.Ticket.Create(ctx, draft.ticket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrSeatUnavailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Seat is already sold for this date"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 		return
 	}
 
-	// Determine passenger ID
-	passengerID := req.PassengerID
-	if passengerID == nil {
-		passenger, _ := h.repos.Passenger.GetByUserID(id)
-		if passenger != nil {
-			pid := passenger.ID
-			passengerID = &pid
-		}
+	c.JSON(http.StatusCreated, h.orderResponse(ctx, order))
+}
+
+// ticketDraft is a ticket to issue together with the passenger to create for
+// it, if the traveller was described inline
+type ticketDraft struct {
+	ticket    *models.Ticket
+	passenger *models.Passenger
+}
+
+// orderLeg is a validated leg of an order with the tickets to issue on it
+type orderLeg struct {
+	stops    []journey.StopTime
+	from, to int
+	drafts   []ticketDraft
+}
+
+func (l *orderLeg) departureStationID() int64 { return l.stops[l.from].StationID }
+func (l *orderLeg) arrivalStationID() int64   { return l.stops[l.to].StationID }
+func (l *orderLeg) departure() time.Time      { return l.stops[l.from].Departure }
+func (l *orderLeg) arrival() time.Time        { return l.stops[l.to].Arrival }
+
+// prepareLeg checks a leg of an order on departureDate, prices its seats and
+// drafts its held tickets. Errors carry the HTTP status to respond with.
+func (h *Handlers) prepareLeg(ctx context.Context, userID int64, req *OrderLegRequest, departureDate time.Time) (*orderLeg, error) {
+	route, err := h.repos.Route.GetByID(ctx, req.RouteID)
+	if err != nil || route == nil {
+		return nil, newHTTPError(http.StatusBadRequest, "Route not found")
+	}
+	runs, err := h.routeRunsOn(ctx, route, departureDate)
+	if err != nil {
+		return nil, err
+	}
+	if !runs {
+		return nil, newHTTPError(http.StatusBadRequest, schedule.ErrNotRunning.Error())
 	}
 
-	// Create ticket with price from request
-	ticketNumber := fmt.Sprintf("TK-%d-%d", order.ID, time.Now().Unix())
-	ticket := &models.Ticket{
-		OrderID:      order.ID,
-		SeatID:       &req.SeatID,
-		PassengerID:  passengerID,
-		DepartureDate: time.Now().AddDate(0, 0, 1), // Tomorrow
-		Price:        req.Price,
-		TicketNumber: ticketNumber,
-		Status:       "ACTIVE",
+	routeStations, err := h.repos.Route.GetStations(ctx, route.ID)
+	if err != nil {
+		return nil, err
 	}
-	if err := h.repos.Ticket.Create(ticket); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
-		return
+	if len(routeStations) < 2 {
+		return nil, newHTTPError(http.StatusBadRequest, "Route has no timetable")
+	}
+	if err := schedule.ValidateTravelDate(departureDate, time.Now(), routeStations[0].TimeZone, h.cfg.BookingHorizonDays); err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, err.Error())
+	}
+	from, to, err := routeSegment(routeStations, req.FromStationID, req.ToStationID)
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, err.Error())
+	}
+	leg := &orderLeg{stops: journey.Timetable(departureDate, routeStations), from: from, to: to}
+	if leg.departure().IsZero() || leg.arrival().IsZero() {
+		return nil, newHTTPError(http.StatusBadRequest, "Route has no timetable")
+	}
+	if leg.departure().Before(time.Now()) {
+		return nil, newHTTPError(http.StatusBadRequest, schedule.ErrAlreadyDeparted.Error())
 	}
 
-	// Get seat and carriage info
-	carriage, _ := h.repos.Carriage.GetByID(seat.CarriageID)
+	routeID := route.ID
+	segment := segmentOf(routeStations, from, to)
+	fromStationID, toStationID := routeStations[from].StationID, routeStations[to].StationID
 
-	// Get route information
-	var routeName, trainNumber, trainType, departureCity, arrivalCity, departureTime, arrivalTime string
-	if route != nil {
-		routeName = route.Name
-		train, _ := h.repos.Train.GetByID(route.TrainID)
-		if train != nil {
-			trainNumber = train.Number
-			trainType = train.Type
+	seen := make(map[int64]bool, len(req.Items))
+	for _, item := range req.Items {
+		if seen[item.SeatID] {
+			return nil, newHTTPError(http.StatusBadRequest, fmt.Sprintf("Seat %d is listed more than once", item.SeatID))
 		}
-		routeStations, _ := h.repos.Route.GetStations(route.ID)
-		if len(routeStations) > 0 {
-			departureStation, _ := h.repos.Station.GetByID(routeStations[0].StationID)
-			if departureStation != nil {
-				departureCity = departureStation.City
-			}
-			if routeStations[0].DepartureTime != nil {
-				departureTime = routeStations[0].DepartureTime.Format("15:04")
-			}
-			if len(routeStations) > 1 {
-				arrivalStation, _ := h.repos.Station.GetByID(routeStations[len(routeStations)-1].StationID)
-				if arrivalStation != nil {
-					arrivalCity = arrivalStation.City
-				}
-				if routeStations[len(routeStations)-1].ArrivalTime != nil {
-					arrivalTime = routeStations[len(routeStations)-1].ArrivalTime.Format("15:04")
-				}
-			}
+		seen[item.SeatID] = true
+
+		seat, err := h.repos.Seat.GetByID(ctx, item.SeatID)
+		if err != nil || seat == nil {
+			return nil, newHTTPError(http.StatusBadRequest, "Seat not found")
+		}
+		carriage, _ := h.repos.Carriage.GetByID(ctx, seat.CarriageID)
+		if carriage == nil || carriage.TrainID != route.TrainID {
+			return nil, newHTTPError(http.StatusBadRequest, "Seat does not belong to this route's train")
 		}
-	}
 
-	response := OrderResponse{
-		ID:           order.ID,
-		UserID:       order.UserID,
-		RouteID:      order.RouteID,
-		RouteName:    routeName,
-		TrainNumber:  trainNumber,
-		TrainType:    trainType,
-		DepartureCity: departureCity,
-		ArrivalCity:   arrivalCity,
-		DepartureTime: departureTime,
-		ArrivalTime:   arrivalTime,
-		CreatedAt:    order.CreatedAt.Format(time.RFC3339),
-		Status:       order.Status,
-		TotalAmount:  order.TotalAmount,
-		Tickets: []TicketResponse{
-			{
-				ID:           ticket.ID,
-				TicketNumber: ticket.TicketNumber,
-				SeatNumber:   &seat.Number,
-				CarriageNumber: func() *int { if carriage != nil { n := carriage.Number; return &n }; return nil }(),
-				Price:        ticket.Price,
+		passengerID, newPassenger, err := h.resolvePassenger(ctx, userID, &item)
+		if err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		category, err := pricing.ParseCategory(item.PassengerCategory)
+		if err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, err.Error())
+		}
+		price, err := pricing.Calculate(pricing.Fare{
+			RoutePrice: route.Price,
+			Multiplier: carriage.Type.FareMultiplier,
+			Legs:       to - from,
+			TotalLegs:  len(routeStations) - 1,
+			Category:   category,
+		})
+		if errors.Is(err, pricing.ErrNoPrice) {
+			return nil, newHTTPError(http.StatusConflict, "Route has no fare and is not on sale")
+		}
+		if err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, "Failed to calculate fare")
+		}
+		if item.ExpectedPrice != nil && !pricing.Matches(*item.ExpectedPrice, price) {
+			return nil, newHTTPErrorWith(http.StatusConflict, "Price has changed", gin.H{"seatId": seat.ID, "price": price})
+		}
+
+		available, err := h.repos.Seat.IsAvailable(ctx, route.ID, seat.ID, departureDate.Format(schedule.DateLayout), segment)
+		if err != nil {
+			return nil, newHTTPError(http.StatusInternalServerError, "Failed to check seat availability")
+		}
+		if !available {
+			return nil, newHTTPErrorWith(http.StatusConflict, "Seat is already sold for this date", gin.H{"seatId": seat.ID})
+		}
+
+		// Seats stay held for the order until it is paid or the hold expires
+		departsAt, arrivesAt := leg.departure(), leg.arrival()
+		leg.drafts = append(leg.drafts, ticketDraft{
+			ticket: &models.Ticket{
+				RouteID:       &routeID,
+				SeatID:        &seat.ID,
+				PassengerID:   passengerID,
+				DepartureDate: departureDate,
+				FromStationID: &fromStationID,
+				ToStationID:   &toStationID,
+				FromStop:      &segment.FromStop,
+				ToStop:        &segment.ToStop,
+				DepartsAt:     &departsAt,
+				ArrivesAt:     &arrivesAt,
+				Price:         price,
+				Status:        models.TicketHeld,
 			},
-		},
+			passenger: newPassenger,
+		})
 	}
+	return leg, nil
+}
 
-	c.JSON(http.StatusCreated, response)
+// resolvePassenger works out who travels on an order item: one of the user's
+// saved passengers, a new companion described inline, or the user themselves
+func (h *Handlers) resolvePassenger(ctx context.Context, userID int64, item *OrderItemRequest) (*int64, *models.Passenger, error) {
+	if item.PassengerID != nil && item.Passenger != nil {
+		return nil, nil, errors.New("passengerId and passenger are mutually exclusive")
+	}
+
+	if item.PassengerID != nil {
+		passenger, _ := h.repos.Passenger.GetByID(ctx, *item.PassengerID)
+		if passenger == nil || passenger.UserID != userID {
+			return nil, nil, errors.New("passenger not found")
+		}
+		return &passenger.ID, nil, nil
+	}
+
+	if item.Passenger != nil {
+		return nil, &models.Passenger{
+			UserID:       userID,
+			FirstName:    item.Passenger.FirstName,
+			LastName:     item.Passenger.LastName,
+			PassportData: item.Passenger.PassportData,
+		}, nil
+	}
+
+	passenger, _ := h.repos.Passenger.GetByUserID(ctx, userID)
+	if passenger == nil {
+		return nil, nil, nil
+	}
+	return &passenger.ID, nil, nil
 }
 
 // GetOrders gets user's orders
@@ -490,98 +1178,27 @@ func (h *Handlers) CreateOrder(c *gin.Context) {
 // @Success 200 {array} OrderResponse
 // @Router /orders [get]
 func (h *Handlers) GetOrders(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
-	orders, err := h.repos.Order.GetByUserID(id)
+	orders, err := h.repos.Order.GetByUserID(ctx, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
 		return
 	}
 
-	var responses []OrderResponse
-	for _, order := range orders {
-		tickets, _ := h.repos.Ticket.GetByOrderID(order.ID)
-		var ticketResponses []TicketResponse
-		for _, ticket := range tickets {
-			var seatNumber, carriageNumber *int
-			if ticket.SeatID != nil {
-				seat, _ := h.repos.Seat.GetByID(*ticket.SeatID)
-				if seat != nil {
-					seatNumber = &seat.Number
-					carriage, _ := h.repos.Carriage.GetByID(seat.CarriageID)
-					if carriage != nil {
-						n := carriage.Number
-						carriageNumber = &n
-					}
-				}
-			}
-			ticketResponses = append(ticketResponses, TicketResponse{
-				ID:             ticket.ID,
-				TicketNumber:   ticket.TicketNumber,
-				SeatNumber:     seatNumber,
-				CarriageNumber: carriageNumber,
-				Price:          ticket.Price,
-			})
-		}
-
-		// Get route information
-		var routeName, trainNumber, trainType, departureCity, arrivalCity, departureTime, arrivalTime string
-		if order.RouteID != nil {
-			route, _ := h.repos.Route.GetByID(*order.RouteID)
-			if route != nil {
-				routeName = route.Name
-				train, _ := h.repos.Train.GetByID(route.TrainID)
-				if train != nil {
-					trainNumber = train.Number
-					trainType = train.Type
-				}
-				routeStations, _ := h.repos.Route.GetStations(route.ID)
-				if len(routeStations) > 0 {
-					departureStation, _ := h.repos.Station.GetByID(routeStations[0].StationID)
-					if departureStation != nil {
-						departureCity = departureStation.City
-					}
-					if routeStations[0].DepartureTime != nil {
-						departureTime = routeStations[0].DepartureTime.Format("15:04")
-					}
-					if len(routeStations) > 1 {
-						arrivalStation, _ := h.repos.Station.GetByID(routeStations[len(routeStations)-1].StationID)
-						if arrivalStation != nil {
-							arrivalCity = arrivalStation.City
-						}
-						if routeStations[len(routeStations)-1].ArrivalTime != nil {
-							arrivalTime = routeStations[len(routeStations)-1].ArrivalTime.Format("15:04")
-						}
-					}
-				}
-			}
-		}
-
-		responses = append(responses, OrderResponse{
-			ID:           order.ID,
-			UserID:       order.UserID,
-			RouteID:      order.RouteID,
-			RouteName:    routeName,
-			TrainNumber:  trainNumber,
-			TrainType:    trainType,
-			DepartureCity: departureCity,
-			ArrivalCity:   arrivalCity,
-			DepartureTime: departureTime,
-			ArrivalTime:   arrivalTime,
-			CreatedAt:    order.CreatedAt.Format(time.RFC3339),
-			Status:       order.Status,
-			TotalAmount:  order.TotalAmount,
-			Tickets:      ticketResponses,
-		})
+	responses := []OrderResponse{}
+	for i := range orders {
+		responses = append(responses, h.orderResponse(ctx, &orders[i]))
 	}
 
 	c.JSON(http.StatusOK, responses)
 }
 
-// DeleteOrder deletes an order
-// @Summary Delete order
-// @Description Delete an unpaid order
+// DeleteOrder cancels an order
+// @Summary Cancel order
+// @Description Cancel an unpaid order and release its seats. Staff with orders:write can cancel any order.
 // @Tags Orders
 // @Security BearerAuth
 // @Param id path int true "Order ID"
@@ -589,8 +1206,10 @@ func (h *Handlers) GetOrders(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /orders/{id} [delete]
 func (h *Handlers) DeleteOrder(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
@@ -600,26 +1219,29 @@ func (h *Handlers) DeleteOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := h.repos.Order.GetByID(orderID)
-	if err != nil || order == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-		return
-	}
-
-	// Check ownership
-	if order.UserID != id {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		order, err := tx.Order.GetByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return newHTTPError(http.StatusNotFound, "Order not found")
+		}
 
-	// Only allow deletion of unpaid orders
-	if order.Status != "PENDING" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only unpaid orders can be deleted"})
-		return
-	}
+		// Check ownership
+		if order.UserID != id && !auth.HasPermission(c.GetStringSlice("permissions"), models.PermOrdersWrite) {
+			return newHTTPError(http.StatusForbidden, "Access denied")
+		}
 
-	if err := h.repos.Order.Delete(orderID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete order"})
+		// Only unpaid orders can be cancelled; the order is kept for its history
+		if err := tx.Order.Transition(ctx, order, models.OrderCancelled, &id); err != nil {
+			return err
+		}
+		_, err = tx.Ticket.UpdateStatusByOrderID(ctx, order.ID, models.TicketHeld, models.TicketCancelled)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to cancel order")
 		return
 	}
 
@@ -628,14 +1250,17 @@ func (h *Handlers) DeleteOrder(c *gin.Context) {
 
 // GetOrder gets order details
 // @Summary Get order details
-// @Description Get detailed information about an order
+// @Description Get detailed information about an order of the user, or any order with orders:read
 // @Tags Orders
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Order ID"
 // @Success 200 {object} OrderResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /orders/{id} [get]
 func (h *Handlers) GetOrder(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
@@ -645,96 +1270,24 @@ func (h *Handlers) GetOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := h.repos.Order.GetByID(orderID)
+	order, err := h.repos.Order.GetByID(ctx, orderID)
 	if err != nil || order == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
 
 	// Check ownership
-	if order.UserID != id {
+	if order.UserID != id && !auth.HasPermission(c.GetStringSlice("permissions"), models.PermOrdersRead) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	tickets, _ := h.repos.Ticket.GetByOrderID(order.ID)
-	var ticketResponses []TicketResponse
-	for _, ticket := range tickets {
-		var seatNumber, carriageNumber *int
-		if ticket.SeatID != nil {
-			seat, _ := h.repos.Seat.GetByID(*ticket.SeatID)
-			if seat != nil {
-				seatNumber = &seat.Number
-				carriage, _ := h.repos.Carriage.GetByID(seat.CarriageID)
-				if carriage != nil {
-					n := carriage.Number
-					carriageNumber = &n
-				}
-			}
-		}
-		ticketResponses = append(ticketResponses, TicketResponse{
-			ID:             ticket.ID,
-			TicketNumber:   ticket.TicketNumber,
-			SeatNumber:     seatNumber,
-			CarriageNumber: carriageNumber,
-			Price:          ticket.Price,
-		})
-	}
-
-	// Get route information
-	var routeName, trainNumber, trainType, departureCity, arrivalCity, departureTime, arrivalTime string
-	if order.RouteID != nil {
-		route, _ := h.repos.Route.GetByID(*order.RouteID)
-		if route != nil {
-			routeName = route.Name
-			train, _ := h.repos.Train.GetByID(route.TrainID)
-			if train != nil {
-				trainNumber = train.Number
-				trainType = train.Type
-			}
-			routeStations, _ := h.repos.Route.GetStations(route.ID)
-			if len(routeStations) > 0 {
-				departureStation, _ := h.repos.Station.GetByID(routeStations[0].StationID)
-				if departureStation != nil {
-					departureCity = departureStation.City
-				}
-				if routeStations[0].DepartureTime != nil {
-					departureTime = routeStations[0].DepartureTime.Format("15:04")
-				}
-				if len(routeStations) > 1 {
-					arrivalStation, _ := h.repos.Station.GetByID(routeStations[len(routeStations)-1].StationID)
-					if arrivalStation != nil {
-						arrivalCity = arrivalStation.City
-					}
-					if routeStations[len(routeStations)-1].ArrivalTime != nil {
-						arrivalTime = routeStations[len(routeStations)-1].ArrivalTime.Format("15:04")
-					}
-				}
-			}
-		}
-	}
-
-	c.JSON(http.StatusOK, OrderResponse{
-		ID:           order.ID,
-		UserID:       order.UserID,
-		RouteID:      order.RouteID,
-		RouteName:    routeName,
-		TrainNumber:  trainNumber,
-		TrainType:    trainType,
-		DepartureCity: departureCity,
-		ArrivalCity:   arrivalCity,
-		DepartureTime: departureTime,
-		ArrivalTime:   arrivalTime,
-		CreatedAt:    order.CreatedAt.Format(time.RFC3339),
-		Status:       order.Status,
-		TotalAmount:  order.TotalAmount,
-		Tickets:      ticketResponses,
-	})
+	c.JSON(http.StatusOK, h.orderResponse(ctx, order))
 }
 
 // PayOrder processes order payment
 // @Summary Pay order
-// @Description Process payment for an order
+// @Description Process payment for an order. Staff with orders:write can take payment for any order.
 // @Tags Orders
 // @Security BearerAuth
 // @Accept json
@@ -742,8 +1295,10 @@ func (h *Handlers) GetOrder(c *gin.Context) {
 // @Param id path int true "Order ID"
 // @Param request body PaymentRequest true "Payment data"
 // @Success 200 {object} PaymentResponse
+// @Failure 409 {object} map[string]string
 // @Router /orders/{id}/pay [post]
 func (h *Handlers) PayOrder(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	id := userID.(int64)
 
@@ -759,35 +1314,210 @@ func (h *Handlers) PayOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := h.repos.Order.GetByID(orderID)
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		order, err := tx.Order.GetByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return newHTTPError(http.StatusNotFound, "Order not found")
+		}
+
+		// Check ownership
+		if order.UserID != id && !auth.HasPermission(c.GetStringSlice("permissions"), models.PermOrdersWrite) {
+			return newHTTPError(http.StatusForbidden, "Access denied")
+		}
+
+		// The seat hold may have run out before the reaper got to the order
+		if order.Status == models.OrderPending && order.ExpiresAt != nil && order.ExpiresAt.Before(time.Now()) {
+			return newHTTPError(http.StatusConflict, "Order has expired")
+		}
+
+		if err := tx.Order.Transition(ctx, order, models.OrderPaid, &id); err != nil {
+			return err
+		}
+
+		// Held seats become sold
+		_, err = tx.Ticket.UpdateStatusByOrderID(ctx, order.ID, models.TicketHeld, models.TicketActive)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update order")
+		return
+	}
+
+	transactionID := fmt.Sprintf("TXN-%d-%d", orderID, time.Now().Unix())
+	c.JSON(http.StatusOK, PaymentResponse{
+		Status:        "PAID",
+		TransactionID: transactionID,
+	})
+}
+
+// RefundOrder refunds tickets of a paid order
+// @Summary Refund order
+// @Description Refund the given tickets of a paid order, or all of its active tickets if none are given. Staff with orders:refund can refund any order.
+// @Tags Orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body RefundRequest false "Tickets to refund"
+// @Success 200 {object} RefundResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /orders/{id}/refund [post]
+func (h *Handlers) RefundOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	id := userID.(int64)
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req RefundRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var response RefundResponse
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		order, err := tx.Order.GetByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return newHTTPError(http.StatusNotFound, "Order not found")
+		}
+
+		// Check ownership
+		if order.UserID != id && !auth.HasPermission(c.GetStringSlice("permissions"), models.PermOrdersRefund) {
+			return newHTTPError(http.StatusForbidden, "Access denied")
+		}
+
+		tickets, err := tx.Ticket.GetByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		byID := make(map[int64]*models.Ticket, len(tickets))
+		for i := range tickets {
+			byID[tickets[i].ID] = &tickets[i]
+		}
+
+		var refund []*models.Ticket
+		if len(req.TicketIDs) == 0 {
+			for i := range tickets {
+				if tickets[i].Status == models.TicketActive {
+					refund = append(refund, &tickets[i])
+				}
+			}
+		}
+		listed := make(map[int64]bool, len(req.TicketIDs))
+		for _, ticketID := range req.TicketIDs {
+			// A repeated ID would be refunded, and paid out, twice
+			if listed[ticketID] {
+				return newHTTPError(http.StatusBadRequest, fmt.Sprintf("Ticket %d is listed more than once", ticketID))
+			}
+			listed[ticketID] = true
+			ticket, ok := byID[ticketID]
+			if !ok {
+				return newHTTPError(http.StatusBadRequest, fmt.Sprintf("Ticket %d does not belong to this order", ticketID))
+			}
+			if ticket.Status != models.TicketActive {
+				return newHTTPError(http.StatusConflict, fmt.Sprintf("Ticket %d cannot be refunded", ticketID))
+			}
+			refund = append(refund, ticket)
+		}
+		if len(refund) == 0 {
+			return newHTTPError(http.StatusConflict, "Order has no tickets to refund")
+		}
+
+		for _, ticket := range refund {
+			ticket.Status = models.TicketRefunded
+			if err := tx.Ticket.Update(ctx, ticket); err != nil {
+				return err
+			}
+			response.RefundedAmount += ticket.Price
+		}
+
+		next := models.OrderRefunded
+		for i := range tickets {
+			if tickets[i].Status == models.TicketActive {
+				next = models.OrderPartiallyRefunded
+				break
+			}
+		}
+		if err := tx.Order.Transition(ctx, order, next, &id); err != nil {
+			return err
+		}
+		response.Status = string(order.Status)
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to refund order")
+		return
+	}
+
+	response.RefundedAmount = pricing.Round(response.RefundedAmount)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetOrderHistory lists the status changes of an order
+// @Summary Get order status history
+// @Description Get every status change of an order, oldest first
+// @Tags Orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} models.OrderStatusChange
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /orders/{id}/history [get]
+func (h *Handlers) GetOrderHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	id := userID.(int64)
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.repos.Order.GetByID(ctx, orderID)
 	if err != nil || order == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
 
 	// Check ownership
-	if order.UserID != id {
+	if order.UserID != id && !auth.HasPermission(c.GetStringSlice("permissions"), models.PermOrdersRead) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	// Update order status
-	order.Status = "PAID"
-	if err := h.repos.Order.Update(order); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
+	history, err := h.repos.Order.GetStatusHistory(ctx, orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get order history"})
 		return
 	}
+	if history == nil {
+		history = []models.OrderStatusChange{}
+	}
 
-	transactionID := fmt.Sprintf("TXN-%d-%d", orderID, time.Now().Unix())
-	c.JSON(http.StatusOK, PaymentResponse{
-		Status:        "PAID",
-		TransactionID: transactionID,
-	})
+	c.JSON(http.StatusOK, history)
 }
 
-// CreateRoute creates a new route (Admin only)
+// CreateRoute creates a new route (requires routes:write)
 // @Summary Create route
-// @Description Create a new route (Admin only)
+// @Description Create a new route (requires routes:write)
 // @Tags Admin
 // @Security BearerAuth
 // @Accept json
@@ -796,17 +1526,27 @@ func (h *Handlers) PayOrder(c *gin.Context) {
 // @Success 201 {object} models.Route
 // @Router /admin/routes [post]
 func (h *Handlers) CreateRoute(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req CreateRouteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.CalendarID != nil {
+		if err := h.checkCalendar(ctx, *req.CalendarID); err != nil {
+			respondError(c, err, "Failed to create route")
+			return
+		}
+	}
+
 	route := &models.Route{
-		Name:    req.Name,
-		TrainID: req.TrainID,
+		Name:       req.Name,
+		TrainID:    req.TrainID,
+		Price:      req.Price,
+		CalendarID: req.CalendarID,
 	}
-	if err := h.repos.Route.Create(route); err != nil {
+	if err := h.repos.Route.Create(ctx, route); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create route"})
 		return
 	}
@@ -814,9 +1554,9 @@ func (h *Handlers) CreateRoute(c *gin.Context) {
 	c.JSON(http.StatusCreated, route)
 }
 
-// UpdateRoute updates a route (Admin only)
+// UpdateRoute updates a route (requires routes:write)
 // @Summary Update route
-// @Description Update a route (Admin only)
+// @Description Update a route (requires routes:write)
 // @Tags Admin
 // @Security BearerAuth
 // @Accept json
@@ -826,6 +1566,7 @@ func (h *Handlers) CreateRoute(c *gin.Context) {
 // @Success 200 {object} models.Route
 // @Router /admin/routes/{id} [put]
 func (h *Handlers) UpdateRoute(c *gin.Context) {
+	ctx := c.Request.Context()
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
@@ -838,7 +1579,7 @@ func (h *Handlers) UpdateRoute(c *gin.Context) {
 		return
 	}
 
-	route, err := h.repos.Route.GetByID(id)
+	route, err := h.repos.Route.GetByID(ctx, id)
 	if err != nil || route == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
 		return
@@ -850,8 +1591,21 @@ func (h *Handlers) UpdateRoute(c *gin.Context) {
 	if req.TrainID != 0 {
 		route.TrainID = req.TrainID
 	}
+	if req.Price != nil {
+		route.Price = *req.Price
+	}
+	if req.CalendarID != nil {
+		route.CalendarID = nil
+		if *req.CalendarID != 0 {
+			if err := h.checkCalendar(ctx, *req.CalendarID); err != nil {
+				respondError(c, err, "Failed to update route")
+				return
+			}
+			route.CalendarID = req.CalendarID
+		}
+	}
 
-	if err := h.repos.Route.Update(route); err != nil {
+	if err := h.repos.Route.Update(ctx, route); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update route"})
 		return
 	}
@@ -859,22 +1613,23 @@ func (h *Handlers) UpdateRoute(c *gin.Context) {
 	c.JSON(http.StatusOK, route)
 }
 
-// DeleteRoute deletes a route (Admin only)
+// DeleteRoute deletes a route (requires routes:write)
 // @Summary Delete route
-// @Description Delete a route (Admin only)
+// @Description Delete a route (requires routes:write)
 // @Tags Admin
 // @Security BearerAuth
 // @Param id path int true "Route ID"
 // @Success 204
 // @Router /admin/routes/{id} [delete]
 func (h *Handlers) DeleteRoute(c *gin.Context) {
+	ctx := c.Request.Context()
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
 		return
 	}
 
-	if err := h.repos.Route.Delete(id); err != nil {
+	if err := h.repos.Route.Delete(ctx, id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete route"})
 		return
 	}
@@ -882,9 +1637,9 @@ func (h *Handlers) DeleteRoute(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// CreateTrain creates a new train (Admin only)
+// CreateTrain creates a new train (requires trains:write)
 // @Summary Create train
-// @Description Create a new train (Admin only)
+// @Description Create a new train (requires trains:write)
 // @Tags Admin
 // @Security BearerAuth
 // @Accept json
@@ -893,6 +1648,7 @@ func (h *Handlers) DeleteRoute(c *gin.Context) {
 // @Success 201 {object} models.Train
 // @Router /admin/trains [post]
 func (h *Handlers) CreateTrain(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req CreateTrainRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -903,7 +1659,7 @@ func (h *Handlers) CreateTrain(c *gin.Context) {
 		Number: req.Number,
 		Type:   req.Type,
 	}
-	if err := h.repos.Train.Create(train); err != nil {
+	if err := h.repos.Train.Create(ctx, train); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create train"})
 		return
 	}
@@ -911,9 +1667,9 @@ func (h *Handlers) CreateTrain(c *gin.Context) {
 	c.JSON(http.StatusCreated, train)
 }
 
-// UpdateTrain updates a train (Admin only)
+// UpdateTrain updates a train (requires trains:write)
 // @Summary Update train
-// @Description Update a train (Admin only)
+// @Description Update a train (requires trains:write)
 // @Tags Admin
 // @Security BearerAuth
 // @Accept json
@@ -923,6 +1679,7 @@ func (h *Handlers) CreateTrain(c *gin.Context) {
 // @Success 200 {object} models.Train
 // @Router /admin/trains/{id} [put]
 func (h *Handlers) UpdateTrain(c *gin.Context) {
+	ctx := c.Request.Context()
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid train ID"})
@@ -935,7 +1692,7 @@ func (h *Handlers) UpdateTrain(c *gin.Context) {
 		return
 	}
 
-	train, err := h.repos.Train.GetByID(id)
+	train, err := h.repos.Train.GetByID(ctx, id)
 	if err != nil || train == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Train not found"})
 		return
@@ -948,7 +1705,7 @@ func (h *Handlers) UpdateTrain(c *gin.Context) {
 		train.Type = req.Type
 	}
 
-	if err := h.repos.Train.Update(train); err != nil {
+	if err := h.repos.Train.Update(ctx, train); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update train"})
 		return
 	}
@@ -956,22 +1713,23 @@ func (h *Handlers) UpdateTrain(c *gin.Context) {
 	c.JSON(http.StatusOK, train)
 }
 
-// DeleteTrain deletes a train (Admin only)
+// DeleteTrain deletes a train (requires trains:write)
 // @Summary Delete train
-// @Description Delete a train (Admin only)
+// @Description Delete a train (requires trains:write)
 // @Tags Admin
 // @Security BearerAuth
 // @Param id path int true "Train ID"
 // @Success 204
 // @Router /admin/trains/{id} [delete]
 func (h *Handlers) DeleteTrain(c *gin.Context) {
+	ctx := c.Request.Context()
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid train ID"})
 		return
 	}
 
-	if err := h.repos.Train.Delete(id); err != nil {
+	if err := h.repos.Train.Delete(ctx, id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete train"})
 		return
 	}
@@ -979,99 +1737,52 @@ func (h *Handlers) DeleteTrain(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// GetAllOrders gets all orders (Admin only)
+// GetTicket finds a ticket by its number, e.g. to check it on board
+// (requires orders:read)
+// @Summary Get ticket by number
+// @Description Get a ticket with its passenger, seat, segment and status by the number printed on it (requires orders:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param number path string true "Ticket number"
+// @Success 200 {object} TicketResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/tickets/{number} [get]
+func (h *Handlers) GetTicket(c *gin.Context) {
+	ctx := c.Request.Context()
+	ticket, err := h.repos.Ticket.GetByNumber(ctx, c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ticket"})
+		return
+	}
+	if ticket == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.ticketResponse(ctx, ticket))
+}
+
+// GetAllOrders gets all orders (requires orders:read)
 // @Summary Get all orders
-// @Description Get all orders in the system (Admin only)
+// @Description Get all orders in the system (requires orders:read)
 // @Tags Admin
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {array} OrderResponse
 // @Router /admin/orders [get]
 func (h *Handlers) GetAllOrders(c *gin.Context) {
-	orders, err := h.repos.Order.GetAll()
+	ctx := c.Request.Context()
+	orders, err := h.repos.Order.GetAll(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
 		return
 	}
 
-	var responses []OrderResponse
-	for _, order := range orders {
-		tickets, _ := h.repos.Ticket.GetByOrderID(order.ID)
-		var ticketResponses []TicketResponse
-		for _, ticket := range tickets {
-			var seatNumber, carriageNumber *int
-			if ticket.SeatID != nil {
-				seat, _ := h.repos.Seat.GetByID(*ticket.SeatID)
-				if seat != nil {
-					seatNumber = &seat.Number
-					carriage, _ := h.repos.Carriage.GetByID(seat.CarriageID)
-					if carriage != nil {
-						n := carriage.Number
-						carriageNumber = &n
-					}
-				}
-			}
-			ticketResponses = append(ticketResponses, TicketResponse{
-				ID:             ticket.ID,
-				TicketNumber:   ticket.TicketNumber,
-				SeatNumber:     seatNumber,
-				CarriageNumber: carriageNumber,
-				Price:          ticket.Price,
-			})
-		}
-
-		// Get route information
-		var routeName, trainNumber, trainType, departureCity, arrivalCity, departureTime, arrivalTime string
-		if order.RouteID != nil {
-			route, _ := h.repos.Route.GetByID(*order.RouteID)
-			if route != nil {
-				routeName = route.Name
-				train, _ := h.repos.Train.GetByID(route.TrainID)
-				if train != nil {
-					trainNumber = train.Number
-					trainType = train.Type
-				}
-				routeStations, _ := h.repos.Route.GetStations(route.ID)
-				if len(routeStations) > 0 {
-					departureStation, _ := h.repos.Station.GetByID(routeStations[0].StationID)
-					if departureStation != nil {
-						departureCity = departureStation.City
-					}
-					if routeStations[0].DepartureTime != nil {
-						departureTime = routeStations[0].DepartureTime.Format("15:04")
-					}
-					if len(routeStations) > 1 {
-						arrivalStation, _ := h.repos.Station.GetByID(routeStations[len(routeStations)-1].StationID)
-						if arrivalStation != nil {
-							arrivalCity = arrivalStation.City
-						}
-						if routeStations[len(routeStations)-1].ArrivalTime != nil {
-							arrivalTime = routeStations[len(routeStations)-1].ArrivalTime.Format("15:04")
-						}
-					}
-				}
-			}
-		}
-
-		responses = append(responses, OrderResponse{
-			ID:           order.ID,
-			UserID:       order.UserID,
-			RouteID:      order.RouteID,
-			RouteName:    routeName,
-			TrainNumber:  trainNumber,
-			TrainType:    trainType,
-			DepartureCity: departureCity,
-			ArrivalCity:   arrivalCity,
-			DepartureTime: departureTime,
-			ArrivalTime:   arrivalTime,
-			CreatedAt:    order.CreatedAt.Format(time.RFC3339),
-			Status:       order.Status,
-			TotalAmount:  order.TotalAmount,
-			Tickets:      ticketResponses,
-		})
+	responses := []OrderResponse{}
+	for i := range orders {
+		responses = append(responses, h.orderResponse(ctx, &orders[i]))
 	}
 
 	c.JSON(http.StatusOK, responses)
 }
-
-
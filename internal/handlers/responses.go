@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/schedule"
+)
+
+// orderResponse builds the API view of an order with its tickets and route
+func (h *Handlers) orderResponse(ctx context.Context, order *models.Order) OrderResponse {
+	tickets, _ := h.repos.Ticket.GetByOrderID(ctx, order.ID)
+	ticketResponses := []TicketResponse{}
+	for _, ticket := range tickets {
+		ticketResponses = append(ticketResponses, h.ticketResponse(ctx, &ticket))
+	}
+
+	// Get route information. Departure and arrival are those of the booked
+	// segment, which may be shorter than the route; a journey with transfers
+	// departs with its first ticket and arrives with its last one.
+	var routeName, trainNumber, trainType, departureCity, arrivalCity, departureTime, arrivalTime string
+	routeID := order.RouteID
+	if routeID == nil && len(tickets) > 0 {
+		routeID = tickets[0].RouteID
+	}
+	if routeID != nil {
+		route, _ := h.repos.Route.GetByID(ctx, *routeID)
+		if route != nil {
+			routeName = route.Name
+			train, _ := h.repos.Train.GetByID(ctx, route.TrainID)
+			if train != nil {
+				trainNumber = train.Number
+				trainType = train.Type
+			}
+		}
+	}
+
+	first, last := &models.Ticket{RouteID: routeID}, &models.Ticket{RouteID: routeID}
+	if len(tickets) > 0 {
+		first, last = &tickets[0], &tickets[len(tickets)-1]
+		departureTime = ticketResponses[0].DepartureTime
+		arrivalTime = ticketResponses[len(ticketResponses)-1].ArrivalTime
+	}
+	if from, _, stops := h.ticketSegment(ctx, first); stops != nil {
+		departureStation, _ := h.repos.Station.GetByID(ctx, stops[from].StationID)
+		if departureStation != nil {
+			departureCity = departureStation.City
+		}
+	}
+	if _, to, stops := h.ticketSegment(ctx, last); stops != nil {
+		arrivalStation, _ := h.repos.Station.GetByID(ctx, stops[to].StationID)
+		if arrivalStation != nil {
+			arrivalCity = arrivalStation.City
+		}
+	}
+
+	return OrderResponse{
+		ID:            order.ID,
+		UserID:        order.UserID,
+		RouteID:       order.RouteID,
+		RouteName:     routeName,
+		TrainNumber:   trainNumber,
+		TrainType:     trainType,
+		DepartureCity: departureCity,
+		ArrivalCity:   arrivalCity,
+		DepartureTime: departureTime,
+		ArrivalTime:   arrivalTime,
+		CreatedAt:     order.CreatedAt.Format(time.RFC3339),
+		ExpiresAt:     formatExpiresAt(order),
+		Status:        string(order.Status),
+		TotalAmount:   order.TotalAmount,
+		Tickets:       ticketResponses,
+	}
+}
+
+func (h *Handlers) ticketResponse(ctx context.Context, ticket *models.Ticket) TicketResponse {
+	response := TicketResponse{
+		ID:            ticket.ID,
+		RouteID:       ticket.RouteID,
+		TicketNumber:  ticket.TicketNumber,
+		PassengerID:   ticket.PassengerID,
+		DepartureDate: ticket.DepartureDate.Format(schedule.DateLayout),
+		FromStationID: ticket.FromStationID,
+		ToStationID:   ticket.ToStationID,
+		Status:        string(ticket.Status),
+		Price:         ticket.Price,
+	}
+	if ticket.FromStationID != nil {
+		station, _ := h.repos.Station.GetByID(ctx, *ticket.FromStationID)
+		if station != nil {
+			response.FromStation = station.Name
+		}
+	}
+	if ticket.ToStationID != nil {
+		station, _ := h.repos.Station.GetByID(ctx, *ticket.ToStationID)
+		if station != nil {
+			response.ToStation = station.Name
+		}
+	}
+	response.DepartureTime, response.ArrivalTime = h.ticketTimes(ctx, ticket)
+	if ticket.RouteID != nil {
+		route, _ := h.repos.Route.GetByID(ctx, *ticket.RouteID)
+		if route != nil {
+			train, _ := h.repos.Train.GetByID(ctx, route.TrainID)
+			if train != nil {
+				response.TrainNumber = train.Number
+			}
+		}
+	}
+	if ticket.SeatID != nil {
+		seat, _ := h.repos.Seat.GetByID(ctx, *ticket.SeatID)
+		if seat != nil {
+			response.SeatNumber = &seat.Number
+			carriage, _ := h.repos.Carriage.GetByID(ctx, seat.CarriageID)
+			if carriage != nil {
+				n := carriage.Number
+				response.CarriageNumber = &n
+			}
+		}
+	}
+	if ticket.PassengerID != nil {
+		passenger, _ := h.repos.Passenger.GetByID(ctx, *ticket.PassengerID)
+		if passenger != nil {
+			response.PassengerName = passenger.FirstName + " " + passenger.LastName
+		}
+	}
+	return response
+}
+
+// ticketSegment returns the stops of the ticket's route and the indexes of
+// its boarding and alighting stops among them. Tickets without stations cover
+// the whole route. stops is nil when the route is unknown.
+func (h *Handlers) ticketSegment(ctx context.Context, ticket *models.Ticket) (int, int, []models.RouteStation) {
+	if ticket.RouteID == nil {
+		return 0, 0, nil
+	}
+	stops, _ := h.repos.Route.GetStations(ctx, *ticket.RouteID)
+	from, to, err := routeSegment(stops, ticket.FromStationID, ticket.ToStationID)
+	if err != nil {
+		return 0, 0, nil
+	}
+	return from, to, stops
+}
+
+// ticketTimes formats when the ticket's train departs and arrives, in the
+// local time of the boarding and alighting stations. Tickets booked before
+// their times were stored take them from the current timetable.
+func (h *Handlers) ticketTimes(ctx context.Context, ticket *models.Ticket) (string, string) {
+	from, to, stops := h.ticketSegment(ctx, ticket)
+	if stops == nil {
+		return "", ""
+	}
+	departure, arrival := ticket.DepartsAt, ticket.ArrivesAt
+	if departure == nil {
+		t := schedule.Departure(ticket.DepartureDate, stops[from])
+		departure = &t
+	}
+	if arrival == nil {
+		t := schedule.Arrival(ticket.DepartureDate, stops[to])
+		arrival = &t
+	}
+	return formatLocal(*departure, stops[from].TimeZone), formatLocal(*arrival, stops[to].TimeZone)
+}
+
+// formatLocal formats t as RFC 3339 in the given station time zone
+func formatLocal(t time.Time, zone string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(schedule.Location(zone)).Format(time.RFC3339)
+}
+
+// formatExpiresAt returns when the seat hold of an unpaid order runs out
+func formatExpiresAt(order *models.Order) string {
+	if order.Status != models.OrderPending || order.ExpiresAt == nil {
+		return ""
+	}
+	return order.ExpiresAt.Format(time.RFC3339)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+	"github.com/project13/backend-stealthisproject/pkg/auth"
+)
+
+// Refresh exchanges a refresh token for new tokens
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and the next refresh token. Each refresh token works once; presenting a used one again revokes its session.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} AuthResponse
+// @Failure 401 {object} map[string]string
+// @Router /auth/refresh [post]
+func (h *Handlers) Refresh(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var response AuthResponse
+	var revoked []models.RevokedToken
+	var reused bool
+	err := h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		reused = false
+		token, err := tx.RefreshToken.GetByHash(ctx, auth.HashToken(req.RefreshToken))
+		if err != nil {
+			return err
+		}
+		if token == nil || !token.ExpiresAt.After(time.Now()) {
+			return newHTTPError(http.StatusUnauthorized, "Invalid or expired refresh token")
+		}
+		session, err := tx.Session.GetByID(ctx, token.SessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil || session.RevokedAt != nil {
+			return newHTTPError(http.StatusUnauthorized, "Invalid or expired refresh token")
+		}
+		if token.UsedAt != nil {
+			// A rotated token coming back means someone else holds a copy;
+			// end the session for both rather than guess who is legitimate
+			reused = true
+			revoked, err = tx.Session.Revoke(ctx, session.ID)
+			return err
+		}
+
+		user, err := tx.User.GetByID(ctx, session.UserID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return newHTTPError(http.StatusUnauthorized, "Invalid or expired refresh token")
+		}
+		if err := tx.RefreshToken.MarkUsed(ctx, token.ID); err != nil {
+			return err
+		}
+		if err := tx.Session.Touch(ctx, session.ID, c.ClientIP(), time.Now().Add(h.cfg.RefreshTokenTTL)); err != nil {
+			return err
+		}
+		response, err = h.issueTokens(c, tx, user, session.ID)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to refresh token")
+		return
+	}
+	if reused {
+		h.revocations.Revoke(revoked...)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; the session has been revoked"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout ends the session of a refresh token
+// @Summary Logout
+// @Description Revoke the session of the refresh token together with its access tokens
+// @Tags Auth
+// @Accept json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /auth/logout [post]
+func (h *Handlers) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.repos.RefreshToken.GetByHash(ctx, auth.HashToken(req.RefreshToken))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if token != nil {
+		revoked, err := h.repos.Session.Revoke(ctx, token.SessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		h.revocations.Revoke(revoked...)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSessions lists the sessions of the current user
+// @Summary List sessions
+// @Description List the devices the authenticated user is logged in on
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Router /users/me/sessions [get]
+func (h *Handlers) GetSessions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	sessions, err := h.repos.Session.GetActiveByUserID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+
+	current := c.GetInt64("session_id")
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		responses = append(responses, SessionResponse{
+			ID:         s.ID,
+			Device:     s.Device,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// DeleteSession revokes a session of the current user
+// @Summary Revoke session
+// @Description Log the authenticated user out on one device, revoking the session's refresh and access tokens
+// @Tags Users
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /users/me/sessions/{id} [delete]
+func (h *Handlers) DeleteSession(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	session, err := h.repos.Session.GetByID(ctx, id)
+	if err != nil || session == nil || session.UserID != c.GetInt64("user_id") || session.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	revoked, err := h.repos.Session.Revoke(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	h.revocations.Revoke(revoked...)
+
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword changes the password of the current user
+// @Summary Change password
+// @Description Change the authenticated user's password. Every session, including the current one, is revoked and the user has to log in again.
+// @Tags Users
+// @Security BearerAuth
+// @Accept json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users/me/password [put]
+func (h *Handlers) ChangePassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordHash, err := h.authService.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var revoked []models.RevokedToken
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		user, err := tx.User.GetByID(ctx, c.GetInt64("user_id"))
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return newHTTPError(http.StatusNotFound, "User not found")
+		}
+		if err := h.authService.VerifyPassword(user.PasswordHash, req.CurrentPassword); err != nil {
+			return newHTTPError(http.StatusForbidden, "Current password is incorrect")
+		}
+
+		user.PasswordHash = passwordHash
+		if err := tx.User.Update(ctx, user); err != nil {
+			return err
+		}
+		revoked, err = tx.Session.RevokeAllByUserID(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to change password")
+		return
+	}
+	h.revocations.Revoke(revoked...)
+
+	c.Status(http.StatusNoContent)
+}
+
+// startSession opens a session for a new login on the device and issues its
+// first tokens
+func (h *Handlers) startSession(c *gin.Context, user *models.User, device string) (AuthResponse, error) {
+	session := &models.Session{
+		UserID:    user.ID,
+		Device:    device,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		ExpiresAt: time.Now().Add(h.cfg.RefreshTokenTTL),
+	}
+
+	var response AuthResponse
+	err := h.repos.WithTx(c.Request.Context(), func(tx *repository.Repositories) error {
+		if err := tx.Session.Create(c.Request.Context(), session); err != nil {
+			return err
+		}
+		var err error
+		response, err = h.issueTokens(c, tx, user, session.ID)
+		return err
+	})
+	return response, err
+}
+
+// issueTokens signs an access token with the user's current permissions for
+// the session and stores the next refresh token with it
+func (h *Handlers) issueTokens(c *gin.Context, repos *repository.Repositories, user *models.User, sessionID int64) (AuthResponse, error) {
+	permissions, err := repos.Role.GetPermissionsByUserID(c.Request.Context(), user.ID)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	accessToken, err := h.authService.GenerateToken(user.ID, sessionID, user.Roles, permissions)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	refreshToken, hash, err := auth.NewToken()
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	err = repos.RefreshToken.Create(c.Request.Context(), &models.RefreshToken{
+		SessionID:       sessionID,
+		TokenHash:       hash,
+		AccessJTI:       accessToken.JTI,
+		AccessExpiresAt: accessToken.ExpiresAt,
+		ExpiresAt:       time.Now().Add(h.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	return AuthResponse{
+		Token:        accessToken.Token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(h.authService.AccessTTL().Seconds()),
+	}, nil
+}
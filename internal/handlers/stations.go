@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+	"github.com/project13/backend-stealthisproject/internal/schedule"
+)
+
+// defaultTimeZone is the zone of stations created without one
+const defaultTimeZone = "Europe/Minsk"
+
+// GetStations lists stations (requires stations:read)
+// @Summary List stations
+// @Description List all stations (requires stations:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Station
+// @Router /admin/stations [get]
+func (h *Handlers) GetStations(c *gin.Context) {
+	stations, err := h.repos.Station.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stations"})
+		return
+	}
+	if stations == nil {
+		stations = []models.Station{}
+	}
+	c.JSON(http.StatusOK, stations)
+}
+
+// GetStation returns a station (requires stations:read)
+// @Summary Get station
+// @Description Get a station (requires stations:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Station ID"
+// @Success 200 {object} models.Station
+// @Failure 404 {object} map[string]string
+// @Router /admin/stations/{id} [get]
+func (h *Handlers) GetStation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station ID"})
+		return
+	}
+
+	station, err := h.repos.Station.GetByID(c.Request.Context(), id)
+	if err != nil || station == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Station not found"})
+		return
+	}
+	c.JSON(http.StatusOK, station)
+}
+
+// CreateStation creates a station (requires stations:write)
+// @Summary Create station
+// @Description Create a station (requires stations:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateStationRequest true "Station data"
+// @Success 201 {object} models.Station
+// @Failure 400 {object} map[string]string
+// @Router /admin/stations [post]
+func (h *Handlers) CreateStation(c *gin.Context) {
+	var req CreateStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = defaultTimeZone
+	}
+	if !schedule.ValidTimeZone(req.TimeZone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown time zone %q", req.TimeZone)})
+		return
+	}
+
+	station := &models.Station{Name: req.Name, City: req.City, TimeZone: req.TimeZone}
+	if err := h.repos.Station.Create(c.Request.Context(), station); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create station"})
+		return
+	}
+	c.JSON(http.StatusCreated, station)
+}
+
+// UpdateStation updates a station (requires stations:write)
+// @Summary Update station
+// @Description Update a station. Changing the time zone moves the trains calling there in absolute time (requires stations:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Station ID"
+// @Param request body UpdateStationRequest true "Station data"
+// @Success 200 {object} models.Station
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/stations/{id} [put]
+func (h *Handlers) UpdateStation(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station ID"})
+		return
+	}
+
+	var req UpdateStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TimeZone != "" && !schedule.ValidTimeZone(req.TimeZone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown time zone %q", req.TimeZone)})
+		return
+	}
+
+	station, err := h.repos.Station.GetByID(ctx, id)
+	if err != nil || station == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Station not found"})
+		return
+	}
+
+	if req.Name != "" {
+		station.Name = req.Name
+	}
+	if req.City != "" {
+		station.City = req.City
+	}
+	if req.TimeZone != "" {
+		station.TimeZone = req.TimeZone
+	}
+
+	if err := h.repos.Station.Update(ctx, station); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update station"})
+		return
+	}
+	c.JSON(http.StatusOK, station)
+}
+
+// DeleteStation deletes a station (requires stations:write)
+// @Summary Delete station
+// @Description Delete a station that no route calls at and no ticket refers to (requires stations:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Station ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/stations/{id} [delete]
+func (h *Handlers) DeleteStation(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station ID"})
+		return
+	}
+
+	station, err := h.repos.Station.GetByID(ctx, id)
+	if err != nil || station == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Station not found"})
+		return
+	}
+
+	if err := h.repos.Station.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrStationInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Station is used by routes or tickets"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete station"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetRouteStops lists the stops of a route (requires routes:read)
+// @Summary List route stops
+// @Description List the stops of a route in order with their local times (requires routes:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Route ID"
+// @Success 200 {array} StopResponse
+// @Failure 404 {object} map[string]string
+// @Router /admin/routes/{id}/stops [get]
+func (h *Handlers) GetRouteStops(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+		return
+	}
+
+	route, err := h.repos.Route.GetByID(ctx, id)
+	if err != nil || route == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	stops, err := h.repos.Route.GetStations(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stops"})
+		return
+	}
+
+	responses := []StopResponse{}
+	for _, stop := range stops {
+		station, _ := h.repos.Station.GetByID(ctx, stop.StationID)
+		responses = append(responses, stopResponse(stop, station))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// AddRouteStop adds a stop to a route (requires routes:write)
+// @Summary Add route stop
+// @Description Add a stop at stopOrder, moving later stops down, or append it when stopOrder is 0 (requires routes:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Route ID"
+// @Param request body StopRequest true "Stop"
+// @Success 200 {array} StopResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/routes/{id}/stops [post]
+func (h *Handlers) AddRouteStop(c *gin.Context) {
+	var req StopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stop, err := stopFromRequest(req.StationID, req.StopOrder, req.ArrivalTime, req.DepartureTime, req.ArrivalDayOffset, req.DepartureDayOffset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.editStops(c, func(stops []models.RouteStation) ([]models.RouteStation, error) {
+		added := stop
+		if added.StopOrder == 0 {
+			added.StopOrder = len(stops) + 1
+		}
+		for i := range stops {
+			if stops[i].StopOrder >= added.StopOrder {
+				stops[i].StopOrder++
+			}
+		}
+		return append(stops, added), nil
+	})
+}
+
+// ReplaceRouteStops replaces the timetable of a route (requires routes:write)
+// @Summary Replace route stops
+// @Description Replace every stop of a route at once, e.g. to reorder them. Stop orders must run from 1 without gaps (requires routes:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Route ID"
+// @Param request body ReplaceStopsRequest true "Stops"
+// @Success 200 {array} StopResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/routes/{id}/stops [put]
+func (h *Handlers) ReplaceRouteStops(c *gin.Context) {
+	var req ReplaceStopsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var replacement []models.RouteStation
+	for _, r := range req.Stops {
+		stop, err := stopFromRequest(r.StationID, r.StopOrder, r.ArrivalTime, r.DepartureTime, r.ArrivalDayOffset, r.DepartureDayOffset)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		replacement = append(replacement, stop)
+	}
+
+	h.editStops(c, func([]models.RouteStation) ([]models.RouteStation, error) {
+		return replacement, nil
+	})
+}
+
+// UpdateRouteStop retimes or moves a stop (requires routes:write)
+// @Summary Update route stop
+// @Description Replace the times of a stop and, when stopOrder is given, move it there (requires routes:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Route ID"
+// @Param stationId path int true "Station ID"
+// @Param request body UpdateStopRequest true "Stop"
+// @Success 200 {array} StopResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/routes/{id}/stops/{stationId} [put]
+func (h *Handlers) UpdateRouteStop(c *gin.Context) {
+	stationID, err := strconv.ParseInt(c.Param("stationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station ID"})
+		return
+	}
+	var req UpdateStopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := stopFromRequest(stationID, req.StopOrder, req.ArrivalTime, req.DepartureTime, req.ArrivalDayOffset, req.DepartureDayOffset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.editStops(c, func(stops []models.RouteStation) ([]models.RouteStation, error) {
+		i := indexOfStop(stops, stationID)
+		if i < 0 {
+			return nil, newHTTPError(http.StatusNotFound, "Stop not found")
+		}
+		position := i
+		if updated.StopOrder != 0 {
+			position = updated.StopOrder - 1
+		}
+		if position >= len(stops) {
+			return nil, newHTTPError(http.StatusBadRequest, schedule.ErrStopOrderGap.Error())
+		}
+		rest := append(stops[:i:i], stops[i+1:]...)
+		moved := append(append(append([]models.RouteStation{}, rest[:position]...), updated), rest[position:]...)
+		return renumberStops(moved), nil
+	})
+}
+
+// DeleteRouteStop removes a stop (requires routes:write)
+// @Summary Delete route stop
+// @Description Remove a stop from a route; later stops move up (requires routes:write)
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Route ID"
+// @Param stationId path int true "Station ID"
+// @Success 200 {array} StopResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/routes/{id}/stops/{stationId} [delete]
+func (h *Handlers) DeleteRouteStop(c *gin.Context) {
+	stationID, err := strconv.ParseInt(c.Param("stationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station ID"})
+		return
+	}
+
+	h.editStops(c, func(stops []models.RouteStation) ([]models.RouteStation, error) {
+		i := indexOfStop(stops, stationID)
+		if i < 0 {
+			return nil, newHTTPError(http.StatusNotFound, "Stop not found")
+		}
+		return renumberStops(append(stops[:i], stops[i+1:]...)), nil
+	})
+}
+
+// editStops applies edit to the stops of the route in the id path parameter
+// and saves the result if it is a valid timetable. Once tickets are booked on
+// the route, stops can only be retimed or appended: booked segments refer to
+// stop orders, which must not change under them.
+func (h *Handlers) editStops(c *gin.Context, edit func(stops []models.RouteStation) ([]models.RouteStation, error)) {
+	ctx := c.Request.Context()
+	routeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+		return
+	}
+
+	var responses []StopResponse
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		route, err := tx.Route.GetByID(ctx, routeID)
+		if err != nil {
+			return err
+		}
+		if route == nil {
+			return newHTTPError(http.StatusNotFound, "Route not found")
+		}
+		current, err := tx.Route.GetStations(ctx, routeID)
+		if err != nil {
+			return err
+		}
+
+		stops, err := edit(append([]models.RouteStation(nil), current...))
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(stops, func(i, j int) bool { return stops[i].StopOrder < stops[j].StopOrder })
+
+		stations := make([]*models.Station, len(stops))
+		for i := range stops {
+			station, err := tx.Station.GetByID(ctx, stops[i].StationID)
+			if err != nil {
+				return err
+			}
+			if station == nil {
+				return newHTTPError(http.StatusBadRequest, fmt.Sprintf("Station %d not found", stops[i].StationID))
+			}
+			stops[i].RouteID = routeID
+			stops[i].TimeZone = station.TimeZone
+			stations[i] = station
+		}
+		if err := schedule.ValidateTimetable(stops); err != nil {
+			return newHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		if renumbered(current, stops) {
+			booked, err := tx.Ticket.HasUpcomingByRouteID(ctx, routeID)
+			if err != nil {
+				return err
+			}
+			if booked {
+				return newHTTPError(http.StatusConflict, "Route has booked tickets; stops can only be retimed or appended")
+			}
+		}
+
+		if err := tx.Route.ReplaceStations(ctx, routeID, stops); err != nil {
+			return err
+		}
+		responses = make([]StopResponse, len(stops))
+		for i := range stops {
+			responses[i] = stopResponse(stops[i], stations[i])
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update stops")
+		return
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// renumbered reports whether any stop of before is gone or has another stop
+// order in after
+func renumbered(before, after []models.RouteStation) bool {
+	order := make(map[int64]int, len(after))
+	for _, stop := range after {
+		order[stop.StationID] = stop.StopOrder
+	}
+	for _, stop := range before {
+		if o, ok := order[stop.StationID]; !ok || o != stop.StopOrder {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOfStop(stops []models.RouteStation, stationID int64) int {
+	for i := range stops {
+		if stops[i].StationID == stationID {
+			return i
+		}
+	}
+	return -1
+}
+
+// renumberStops sets the stop orders of stops to their positions
+func renumberStops(stops []models.RouteStation) []models.RouteStation {
+	for i := range stops {
+		stops[i].StopOrder = i + 1
+	}
+	return stops
+}
+
+func stopFromRequest(stationID int64, stopOrder int, arrival, departure *string, arrivalDayOffset, departureDayOffset int) (models.RouteStation, error) {
+	stop := models.RouteStation{
+		StationID:          stationID,
+		StopOrder:          stopOrder,
+		ArrivalDayOffset:   arrivalDayOffset,
+		DepartureDayOffset: departureDayOffset,
+	}
+	if stopOrder < 0 {
+		return stop, errors.New("stopOrder must not be negative")
+	}
+	if arrival != nil {
+		clock, err := schedule.ParseClock(*arrival)
+		if err != nil {
+			return stop, err
+		}
+		stop.ArrivalTime = &clock
+	}
+	if departure != nil {
+		clock, err := schedule.ParseClock(*departure)
+		if err != nil {
+			return stop, err
+		}
+		stop.DepartureTime = &clock
+	}
+	return stop, nil
+}
+
+func stopResponse(stop models.RouteStation, station *models.Station) StopResponse {
+	response := StopResponse{
+		StationID:          stop.StationID,
+		TimeZone:           stop.TimeZone,
+		StopOrder:          stop.StopOrder,
+		ArrivalDayOffset:   stop.ArrivalDayOffset,
+		DepartureDayOffset: stop.DepartureDayOffset,
+	}
+	if station != nil {
+		response.Station = station.Name
+		response.City = station.City
+	}
+	if stop.ArrivalTime != nil {
+		response.ArrivalTime = stop.ArrivalTime.Format("15:04")
+	}
+	if stop.DepartureTime != nil {
+		response.DepartureTime = stop.DepartureTime.Format("15:04")
+	}
+	return response
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+// GetUsers lists users with their roles
+// @Summary List users
+// @Description List users with their roles, optionally only those holding a role (requires users:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param role query string false "Role name"
+// @Success 200 {array} models.User
+// @Router /admin/users [get]
+func (h *Handlers) GetUsers(c *gin.Context) {
+	users, err := h.repos.User.GetAll(c.Request.Context(), c.Query("role"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		return
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser returns a user with their roles
+// @Summary Get user
+// @Description Get a user with their roles (requires users:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id} [get]
+func (h *Handlers) GetUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.repos.User.GetByID(c.Request.Context(), id)
+	if err != nil || user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// SetUserRoles replaces the roles of a user
+// @Summary Set user roles
+// @Description Replace the roles of a user; an empty list makes them a passenger. The user's access tokens are revoked so the next refresh carries the new permissions. Users can't change their own roles (requires users:write).
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body SetUserRolesRequest true "Role names"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/roles [put]
+func (h *Handlers) SetUserRoles(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SetUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUserID := c.GetInt64("user_id")
+	if id == currentUserID {
+		// Keeps admins from locking themselves out
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't change your own roles"})
+		return
+	}
+
+	var user *models.User
+	var revoked []models.RevokedToken
+	err = h.repos.WithTx(ctx, func(tx *repository.Repositories) error {
+		var err error
+		user, err = tx.User.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return newHTTPError(http.StatusNotFound, "User not found")
+		}
+
+		names := uniqueTrimmed(req.Roles)
+		roles, err := tx.Role.GetByNames(ctx, names)
+		if err != nil {
+			return err
+		}
+		if len(roles) != len(names) {
+			return newHTTPError(http.StatusBadRequest, "Unknown role: "+strings.Join(unknownRoles(names, roles), ", "))
+		}
+
+		roleIDs := make([]int64, 0, len(roles))
+		user.Roles = make([]string, 0, len(roles))
+		for _, r := range roles {
+			roleIDs = append(roleIDs, r.ID)
+			user.Roles = append(user.Roles, r.Name)
+		}
+		if err := tx.Role.SetUserRoles(ctx, id, roleIDs, currentUserID); err != nil {
+			return err
+		}
+		revoked, err = tx.Session.RevokeAccessTokensByUserID(ctx, id)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to set user roles")
+		return
+	}
+	h.revocations.Revoke(revoked...)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetRoles lists the roles and the permissions they grant
+// @Summary List roles
+// @Description List the roles that can be assigned to users with the permissions each grants (requires users:read)
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Role
+// @Router /admin/roles [get]
+func (h *Handlers) GetRoles(c *gin.Context) {
+	roles, err := h.repos.Role.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get roles"})
+		return
+	}
+	if roles == nil {
+		roles = []models.Role{}
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+func unknownRoles(names []string, roles []models.Role) []string {
+	known := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		known[r.Name] = true
+	}
+	var unknown []string
+	for _, name := range names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,53 @@
+package holds
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+// Reaper periodically expires unpaid orders whose seat hold has run out,
+// which releases their seats back into inventory
+type Reaper struct {
+	orders   repository.OrderRepository
+	interval time.Duration
+}
+
+func NewReaper(orders repository.OrderRepository, interval time.Duration) *Reaper {
+	return &Reaper{
+		orders:   orders,
+		interval: interval,
+	}
+}
+
+// Run releases expired holds every interval until ctx is cancelled
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.reap(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context) {
+	n, err := r.orders.ExpirePending(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Failed to release expired seat holds: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Released seat holds of %d expired order(s)", n)
+	}
+}
@@ -0,0 +1,45 @@
+package holds
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+type fakeOrderRepository struct {
+	repository.OrderRepository
+	calls atomic.Int32
+}
+
+func (f *fakeOrderRepository) ExpirePending(ctx context.Context) (int64, error) {
+	f.calls.Add(1)
+	return 0, nil
+}
+
+func TestReaper_RunStopsOnCancel(t *testing.T) {
+	orders := &fakeOrderRepository{}
+	reaper := NewReaper(orders, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reaper.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Reaper did not stop after context cancellation")
+	}
+
+	if orders.calls.Load() < 2 {
+		t.Errorf("Expected reaper to run repeatedly, ran %d time(s)", orders.calls.Load())
+	}
+}
@@ -0,0 +1,301 @@
+// Package journey plans trips between cities that may change trains on the
+// way. The search is round based in the style of RAPTOR: round k finds the
+// earliest arrival at every station using at most k trains.
+package journey
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/pricing"
+	"github.com/project13/backend-stealthisproject/internal/schedule"
+)
+
+// StopTime is a call of a trip at a station. Arrival is zero at the first
+// stop and Departure is zero at the last one.
+type StopTime struct {
+	StationID int64
+	StopOrder int
+	Arrival   time.Time
+	Departure time.Time
+}
+
+// Trip is a run of a route on a service date
+type Trip struct {
+	RouteID int64
+	// Price is the adult fare in the base class for the whole route
+	Price float64
+	Stops []StopTime
+}
+
+// Timetable turns the stops of a route into the stop times of its run that
+// leaves the first station on date
+func Timetable(date time.Time, stops []models.RouteStation) []StopTime {
+	result := make([]StopTime, len(stops))
+	for i, stop := range stops {
+		result[i] = StopTime{StationID: stop.StationID, StopOrder: stop.StopOrder}
+		if i > 0 {
+			result[i].Arrival = schedule.Arrival(date, stop)
+		}
+		if i < len(stops)-1 {
+			result[i].Departure = schedule.Departure(date, stop)
+		}
+	}
+	return result
+}
+
+// Leg is the part of an itinerary spent on one trip, from the stop at index
+// From to the stop at index To
+type Leg struct {
+	Trip  *Trip
+	From  int
+	To    int
+	Price float64
+}
+
+func (l Leg) Departure() time.Time { return l.Trip.Stops[l.From].Departure }
+func (l Leg) Arrival() time.Time   { return l.Trip.Stops[l.To].Arrival }
+
+// Itinerary is a journey made of one or more consecutive legs
+type Itinerary struct {
+	Legs []Leg
+}
+
+func (it Itinerary) Departure() time.Time    { return it.Legs[0].Departure() }
+func (it Itinerary) Arrival() time.Time      { return it.Legs[len(it.Legs)-1].Arrival() }
+func (it Itinerary) Duration() time.Duration { return it.Arrival().Sub(it.Departure()) }
+func (it Itinerary) Transfers() int          { return len(it.Legs) - 1 }
+
+// Price is the adult base-class fare of all legs
+func (it Itinerary) Price() float64 {
+	var total float64
+	for _, leg := range it.Legs {
+		total += leg.Price
+	}
+	return pricing.Round(total)
+}
+
+func (it Itinerary) key() string {
+	parts := make([]string, len(it.Legs))
+	for i, leg := range it.Legs {
+		parts[i] = fmt.Sprintf("%d:%d-%d@%s", leg.Trip.RouteID, leg.From, leg.To, leg.Departure().Format(time.RFC3339))
+	}
+	return strings.Join(parts, "/")
+}
+
+// Options tune the search
+type Options struct {
+	// MaxTransfers is the most train changes an itinerary may have
+	MaxTransfers int
+	// MinTransferTime is the least time between arriving on one train and
+	// departing on the next
+	MinTransferTime time.Duration
+	// Limit caps the number of itineraries returned; 0 means no limit
+	Limit int
+}
+
+// Plan finds itineraries from any origin station to any destination station
+// that depart at or after earliest. For every departure time it keeps the
+// itineraries not beaten by another one that leaves no earlier, arrives no
+// later and changes trains no more often. Results are ordered by arrival
+// time, then duration, then price.
+func Plan(trips []Trip, origins, destinations []int64, earliest time.Time, opts Options) []Itinerary {
+	originSet := make(map[int64]bool, len(origins))
+	for _, id := range origins {
+		originSet[id] = true
+	}
+	destinationSet := make(map[int64]bool, len(destinations))
+	for _, id := range destinations {
+		destinationSet[id] = true
+	}
+
+	// Every distinct departure from the origin starts a search of its own,
+	// so that later departures are found even if an earlier one arrives first
+	var starts []time.Time
+	seenStart := make(map[time.Time]bool)
+	for _, trip := range trips {
+		for _, stop := range trip.Stops {
+			if originSet[stop.StationID] && !stop.Departure.IsZero() && !stop.Departure.Before(earliest) && !seenStart[stop.Departure] {
+				seenStart[stop.Departure] = true
+				starts = append(starts, stop.Departure)
+			}
+		}
+	}
+
+	var found []Itinerary
+	seen := make(map[string]bool)
+	for _, start := range starts {
+		for _, it := range search(trips, originSet, destinationSet, start, opts) {
+			if k := it.key(); !seen[k] {
+				seen[k] = true
+				found = append(found, it)
+			}
+		}
+	}
+
+	result := paretoFront(found)
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Arrival().Equal(b.Arrival()) {
+			return a.Arrival().Before(b.Arrival())
+		}
+		if a.Duration() != b.Duration() {
+			return a.Duration() < b.Duration()
+		}
+		return a.Price() < b.Price()
+	})
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+	}
+	return result
+}
+
+// label records how a station was reached in a round
+type label struct {
+	arrival time.Time
+	leg     *Leg
+}
+
+// search runs the rounds for a single start time and returns, per number of
+// trains, the itinerary that reaches a destination earliest if it beats the
+// ones found with fewer trains
+func search(trips []Trip, origins, destinations map[int64]bool, start time.Time, opts Options) []Itinerary {
+	rounds := []map[int64]label{make(map[int64]label)}
+	best := make(map[int64]time.Time)
+	marked := make(map[int64]bool)
+	for id := range origins {
+		rounds[0][id] = label{arrival: start}
+		best[id] = start
+		marked[id] = true
+	}
+
+	var result []Itinerary
+	var bestAtDestination time.Time
+	for k := 1; k <= opts.MaxTransfers+1 && len(marked) > 0; k++ {
+		current := make(map[int64]label)
+		nextMarked := make(map[int64]bool)
+
+		for t := range trips {
+			trip := &trips[t]
+			boarded := -1
+			for i, stop := range trip.Stops {
+				if boarded >= 0 && !stop.Arrival.IsZero() {
+					if b, ok := best[stop.StationID]; !ok || stop.Arrival.Before(b) {
+						if bestAtDestination.IsZero() || stop.Arrival.Before(bestAtDestination) {
+							current[stop.StationID] = label{arrival: stop.Arrival, leg: &Leg{Trip: trip, From: boarded, To: i}}
+							best[stop.StationID] = stop.Arrival
+							nextMarked[stop.StationID] = true
+						}
+					}
+				}
+				if boarded >= 0 || !marked[stop.StationID] || stop.Departure.IsZero() {
+					continue
+				}
+				if k == 1 {
+					// The first train of this search leaves exactly at start;
+					// later ones are covered by their own searches
+					if stop.Departure.Equal(start) {
+						boarded = i
+					}
+					continue
+				}
+				ready := reached(rounds, k-1, stop.StationID).arrival.Add(opts.MinTransferTime)
+				if !stop.Departure.Before(ready) {
+					boarded = i
+				}
+			}
+		}
+
+		rounds = append(rounds, current)
+		marked = nextMarked
+
+		// The best arrival at a destination reached with exactly k trains
+		var arrival time.Time
+		var at int64
+		for id, l := range current {
+			if !destinations[id] {
+				continue
+			}
+			if arrival.IsZero() || l.arrival.Before(arrival) || (l.arrival.Equal(arrival) && id < at) {
+				arrival, at = l.arrival, id
+			}
+		}
+		if !arrival.IsZero() && (bestAtDestination.IsZero() || arrival.Before(bestAtDestination)) {
+			bestAtDestination = arrival
+			if it, ok := reconstruct(rounds, k, at); ok {
+				result = append(result, it)
+			}
+		}
+	}
+	return result
+}
+
+// reached returns the label of station as of round k, i.e. the latest round
+// up to k in which the station's arrival was improved
+func reached(rounds []map[int64]label, k int, station int64) label {
+	for r := k; r >= 0; r-- {
+		if l, ok := rounds[r][station]; ok {
+			return l
+		}
+	}
+	return label{}
+}
+
+func reconstruct(rounds []map[int64]label, k int, station int64) (Itinerary, bool) {
+	var legs []Leg
+	for r := k; r > 0; {
+		l, ok := rounds[r][station]
+		if !ok {
+			r--
+			continue
+		}
+		if l.leg == nil {
+			break
+		}
+		legs = append([]Leg{*l.leg}, legs...)
+		station = l.leg.Trip.Stops[l.leg.From].StationID
+		r--
+	}
+	if len(legs) == 0 {
+		return Itinerary{}, false
+	}
+	for i := range legs {
+		price, err := pricing.Calculate(pricing.Fare{
+			RoutePrice: legs[i].Trip.Price,
+			Legs:       legs[i].To - legs[i].From,
+			TotalLegs:  len(legs[i].Trip.Stops) - 1,
+		})
+		if err != nil {
+			return Itinerary{}, false
+		}
+		legs[i].Price = price
+	}
+	return Itinerary{Legs: legs}, true
+}
+
+// paretoFront drops itineraries that another one beats on departure,
+// arrival and transfers
+func paretoFront(its []Itinerary) []Itinerary {
+	var front []Itinerary
+	for i, a := range its {
+		dominated := false
+		for j, b := range its {
+			if i == j {
+				continue
+			}
+			noWorse := !b.Departure().Before(a.Departure()) && !b.Arrival().After(a.Arrival()) && b.Transfers() <= a.Transfers()
+			better := b.Departure().After(a.Departure()) || b.Arrival().Before(a.Arrival()) || b.Transfers() < a.Transfers()
+			if noWorse && better {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, a)
+		}
+	}
+	return front
+}
@@ -0,0 +1,114 @@
+package journey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+var day = time.Date(2030, 5, 10, 0, 0, 0, 0, time.UTC)
+
+func clock(s string) *time.Time {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func at(s string) time.Time {
+	return day.Add(clock(s).Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+// trip builds a trip calling at stations with (arrival, departure) pairs
+func trip(routeID int64, price float64, calls ...interface{}) Trip {
+	var stops []models.RouteStation
+	for i := 0; i < len(calls); i += 3 {
+		stop := models.RouteStation{RouteID: routeID, StationID: calls[i].(int64), StopOrder: len(stops) + 1}
+		if arr := calls[i+1].(string); arr != "" {
+			stop.ArrivalTime = clock(arr)
+		}
+		if dep := calls[i+2].(string); dep != "" {
+			stop.DepartureTime = clock(dep)
+		}
+		stops = append(stops, stop)
+	}
+	return Trip{RouteID: routeID, Price: price, Stops: Timetable(day, stops)}
+}
+
+const (
+	grodno int64 = iota + 1
+	minsk
+	gomel
+)
+
+func network() []Trip {
+	return []Trip{
+		trip(1, 20, grodno, "", "08:00", minsk, "10:00", ""),
+		trip(2, 15, minsk, "", "10:30", gomel, "12:00", ""),
+		trip(3, 40, grodno, "", "07:00", minsk, "09:58", "10:00", gomel, "13:00", ""),
+		trip(4, 15, minsk, "", "10:05", gomel, "11:30", ""),
+	}
+}
+
+func TestPlan_FindsConnection(t *testing.T) {
+	its := Plan(network(), []int64{grodno}, []int64{gomel}, day, Options{MaxTransfers: 2, MinTransferTime: 10 * time.Minute})
+
+	if len(its) != 2 {
+		t.Fatalf("Expected 2 itineraries, got %d", len(its))
+	}
+
+	first := its[0]
+	if first.Transfers() != 1 || first.Legs[0].Trip.RouteID != 1 || first.Legs[1].Trip.RouteID != 2 {
+		t.Errorf("Expected connection 1 -> 2 first, got %+v", first.Legs)
+	}
+	if !first.Arrival().Equal(at("12:00")) {
+		t.Errorf("Expected arrival at 12:00, got %s", first.Arrival())
+	}
+	if first.Price() != 35 {
+		t.Errorf("Expected price 35, got %v", first.Price())
+	}
+
+	direct := its[1]
+	if direct.Transfers() != 0 || direct.Legs[0].Trip.RouteID != 3 {
+		t.Errorf("Expected direct route 3 second, got %+v", direct.Legs)
+	}
+}
+
+func TestPlan_MaxTransfers(t *testing.T) {
+	its := Plan(network(), []int64{grodno}, []int64{gomel}, day, Options{MaxTransfers: 0, MinTransferTime: 10 * time.Minute})
+
+	if len(its) != 1 || its[0].Legs[0].Trip.RouteID != 3 {
+		t.Fatalf("Expected only the direct route, got %d itineraries", len(its))
+	}
+}
+
+func TestPlan_MinTransferTime(t *testing.T) {
+	its := Plan(network(), []int64{grodno}, []int64{gomel}, day, Options{MaxTransfers: 2, MinTransferTime: 45 * time.Minute})
+
+	for _, it := range its {
+		if it.Transfers() > 0 {
+			t.Errorf("Expected no connection with a 45 minute transfer time, got %+v", it.Legs)
+		}
+	}
+}
+
+func TestPlan_Earliest(t *testing.T) {
+	its := Plan(network(), []int64{grodno}, []int64{gomel}, at("07:30"), Options{MaxTransfers: 2, MinTransferTime: 10 * time.Minute})
+
+	if len(its) != 1 || its[0].Legs[0].Trip.RouteID != 1 {
+		t.Fatalf("Expected only the connection leaving after 07:30, got %d itineraries", len(its))
+	}
+}
+
+func TestTimetable_Overnight(t *testing.T) {
+	stops := Timetable(day, []models.RouteStation{
+		{StationID: minsk, DepartureTime: clock("22:00")},
+		{StationID: gomel, ArrivalTime: clock("01:30"), ArrivalDayOffset: 1},
+	})
+
+	if !stops[1].Arrival.Equal(day.AddDate(0, 0, 1).Add(90 * time.Minute)) {
+		t.Errorf("Expected arrival on the next day, got %s", stops[1].Arrival)
+	}
+}
@@ -0,0 +1,174 @@
+// Package layout describes how the seats of a carriage type are arranged
+package layout
+
+import (
+	"errors"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+// MaxSeats bounds the number of seats in a carriage
+const MaxSeats = 200
+
+// OpenSeatsPerRow is the number of open seats in a row, two on each side of
+// the aisle
+const OpenSeatsPerRow = 4
+
+var (
+	ErrNegativeSeats    = errors.New("seat counts can't be negative")
+	ErrNoSeats          = errors.New("layout has no seats")
+	ErrTooManySeats     = errors.New("layout has too many seats")
+	ErrCompartmentSeats = errors.New("compartments and seats per compartment must be given together")
+	ErrSideSeats        = errors.New("side seats need two compartments for every pair")
+)
+
+// Layout is the seating plan of a carriage type. Seats 1..Compartments*
+// SeatsPerCompartment are numbered compartment by compartment; side seats,
+// if any, follow them, and open seats without a compartment come last.
+// An accessible carriage has seats for passengers with reduced mobility.
+type Layout struct {
+	Compartments        int  `json:"compartments"`
+	SeatsPerCompartment int  `json:"seatsPerCompartment"`
+	SideSeats           int  `json:"sideSeats"`
+	OpenSeats           int  `json:"openSeats"`
+	Accessible          bool `json:"accessible"`
+}
+
+// ForType returns the layout of a carriage type; types with the
+// WHEELCHAIR_ACCESS amenity are accessible
+func ForType(t models.CarriageType) Layout {
+	return Layout{
+		Compartments:        t.Compartments,
+		SeatsPerCompartment: t.SeatsPerCompartment,
+		SideSeats:           t.SideSeats,
+		OpenSeats:           t.OpenSeats,
+		Accessible:          t.HasAmenity(models.AmenityWheelchairAccess),
+	}
+}
+
+// Validate checks that the layout describes a carriage that can be built
+func (l Layout) Validate() error {
+	switch {
+	case l.Compartments < 0 || l.SeatsPerCompartment < 0 || l.SideSeats < 0 || l.OpenSeats < 0:
+		return ErrNegativeSeats
+	case (l.Compartments == 0) != (l.SeatsPerCompartment == 0):
+		return ErrCompartmentSeats
+	case l.SideSeats > 2*l.Compartments:
+		return ErrSideSeats
+	case l.Capacity() == 0:
+		return ErrNoSeats
+	case l.Capacity() > MaxSeats:
+		return ErrTooManySeats
+	}
+	return nil
+}
+
+// Capacity is the number of seats in the carriage
+func (l Layout) Capacity() int {
+	return l.Compartments*l.SeatsPerCompartment + l.SideSeats + l.OpenSeats
+}
+
+// Place returns the position and compartment of seat number in the layout.
+// In compartments odd numbers are lower berths and even numbers upper ones;
+// side berths run from the end of the carriage back, so the first pair sits
+// opposite the last compartment. Open seats have neither a position nor a
+// compartment. ok is false for numbers outside the layout.
+func (l Layout) Place(number int) (position models.SeatPosition, compartment int, ok bool) {
+	if number < 1 || number > l.Capacity() {
+		return "", 0, false
+	}
+
+	inCompartments := l.Compartments * l.SeatsPerCompartment
+	if number <= inCompartments {
+		compartment = (number-1)/l.SeatsPerCompartment + 1
+		position = models.SeatLower
+		if l.SeatsPerCompartment > 2 && number%2 == 0 {
+			position = models.SeatUpper
+		}
+		return position, compartment, true
+	}
+
+	if number > inCompartments+l.SideSeats {
+		return "", 0, true
+	}
+	side := number - inCompartments - 1
+	compartment = l.Compartments - side/2
+	position = models.SeatSideLower
+	if side%2 == 1 {
+		position = models.SeatSideUpper
+	}
+	return position, compartment, true
+}
+
+// Orient returns the placement and direction of seat number, nil where they
+// don't apply, for a carriage running with seat 1 at the front. The first
+// half of a compartment's berths face forward and the rest back; berths lie
+// across the carriage, so they are neither window nor aisle. Side berths lie
+// along the window and face neither way. Open seats come in rows of
+// OpenSeatsPerRow with the window seats on the outside, and face the middle
+// of the carriage.
+func (l Layout) Orient(number int) (*models.SeatPlacement, *models.TravelDirection) {
+	if number < 1 || number > l.Capacity() {
+		return nil, nil
+	}
+
+	inCompartments := l.Compartments * l.SeatsPerCompartment
+	if number <= inCompartments {
+		direction := models.FacingForward
+		if (number-1)%l.SeatsPerCompartment >= (l.SeatsPerCompartment+1)/2 {
+			direction = models.FacingBackward
+		}
+		return nil, &direction
+	}
+	if number <= inCompartments+l.SideSeats {
+		placement := models.SeatWindow
+		return &placement, nil
+	}
+
+	open := number - inCompartments - l.SideSeats - 1
+	placement := models.SeatAisle
+	if column := open % OpenSeatsPerRow; column == 0 || column == OpenSeatsPerRow-1 {
+		placement = models.SeatWindow
+	}
+	rows := (l.OpenSeats + OpenSeatsPerRow - 1) / OpenSeatsPerRow
+	direction := models.FacingForward
+	if open/OpenSeatsPerRow < rows/2 {
+		direction = models.FacingBackward
+	}
+	return &placement, &direction
+}
+
+// Access reports whether seat number has room for a wheelchair and whether
+// it is meant for passengers with reduced mobility. In an accessible carriage
+// those are the lower berths of the first compartment or, in a carriage
+// without compartments, the first row of open seats, with the wheelchair
+// space beside its first two.
+func (l Layout) Access(number int) (wheelchairSpace, reducedMobility bool) {
+	if !l.Accessible || number < 1 || number > l.Capacity() {
+		return false, false
+	}
+	if l.Compartments > 0 {
+		position, compartment, _ := l.Place(number)
+		first := compartment == 1 && position == models.SeatLower
+		return first, first
+	}
+	return number <= 2, number <= OpenSeatsPerRow
+}
+
+// Seats returns every seat of a carriage with this layout, numbered from 1,
+// with the position, compartment, placement, direction and accessibility the
+// layout gives it
+func (l Layout) Seats(carriageID int64) []models.Seat {
+	seats := make([]models.Seat, 0, l.Capacity())
+	for number := 1; number <= l.Capacity(); number++ {
+		seat := models.Seat{CarriageID: carriageID, Number: number}
+		if position, compartment, _ := l.Place(number); compartment != 0 {
+			seat.Position = &position
+			seat.Compartment = &compartment
+		}
+		seat.Placement, seat.Direction = l.Orient(number)
+		seat.WheelchairSpace, seat.ReducedMobility = l.Access(number)
+		seats = append(seats, seat)
+	}
+	return seats
+}
@@ -0,0 +1,194 @@
+package layout
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+var (
+	platskart = Layout{Compartments: 9, SeatsPerCompartment: 4, SideSeats: 18}
+	kupe      = Layout{Compartments: 9, SeatsPerCompartment: 4}
+	sv        = Layout{Compartments: 9, SeatsPerCompartment: 2}
+	seating   = Layout{OpenSeats: 62}
+)
+
+func TestForType(t *testing.T) {
+	l := ForType(models.CarriageType{Name: "Плацкарт", Compartments: 9, SeatsPerCompartment: 4, SideSeats: 18, FareMultiplier: 1})
+	if l != platskart {
+		t.Errorf("Expected %+v, got %+v", platskart, l)
+	}
+}
+
+func TestForType_Accessible(t *testing.T) {
+	l := ForType(models.CarriageType{Compartments: 9, SeatsPerCompartment: 4, Amenities: []string{"Bedding", "wheelchair_access"}})
+	if !l.Accessible {
+		t.Error("Expected a type with WHEELCHAIR_ACCESS to be accessible")
+	}
+}
+
+func TestLayout_Capacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		capacity int
+	}{
+		{"platskart", platskart, 54},
+		{"kupe", kupe, 36},
+		{"sv", sv, 18},
+		{"seating", seating, 62},
+	}
+
+	for _, tt := range tests {
+		if got := tt.layout.Capacity(); got != tt.capacity {
+			t.Errorf("%s: Capacity() = %d, want %d", tt.name, got, tt.capacity)
+		}
+	}
+}
+
+func TestLayout_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout Layout
+		want   error
+	}{
+		{"platskart", platskart, nil},
+		{"seating", seating, nil},
+		{"empty", Layout{}, ErrNoSeats},
+		{"negative", Layout{OpenSeats: -1}, ErrNegativeSeats},
+		{"compartments without seats", Layout{Compartments: 9}, ErrCompartmentSeats},
+		{"side seats without compartments", Layout{SideSeats: 2, OpenSeats: 40}, ErrSideSeats},
+		{"too many seats", Layout{OpenSeats: MaxSeats + 1}, ErrTooManySeats},
+	}
+
+	for _, tt := range tests {
+		if err := tt.layout.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLayout_Place(t *testing.T) {
+	mixed := Layout{Compartments: 2, SeatsPerCompartment: 4, SideSeats: 4, OpenSeats: 10}
+
+	tests := []struct {
+		name        string
+		layout      Layout
+		number      int
+		position    models.SeatPosition
+		compartment int
+		ok          bool
+	}{
+		{"first lower berth", platskart, 1, models.SeatLower, 1, true},
+		{"upper berth", platskart, 2, models.SeatUpper, 1, true},
+		{"last compartment", platskart, 36, models.SeatUpper, 9, true},
+		{"first side berth", platskart, 37, models.SeatSideLower, 9, true},
+		{"last side berth", platskart, 54, models.SeatSideUpper, 1, true},
+		{"out of range", platskart, 55, "", 0, false},
+		{"sleeper has no upper berths", sv, 2, models.SeatLower, 1, true},
+		{"sleeper last seat", sv, 18, models.SeatLower, 9, true},
+		{"open seat", seating, 1, "", 0, true},
+		{"open seat after side berths", mixed, 13, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		position, compartment, ok := tt.layout.Place(tt.number)
+		if position != tt.position || compartment != tt.compartment || ok != tt.ok {
+			t.Errorf("%s: Place(%d) = %s, %d, %v; want %s, %d, %v",
+				tt.name, tt.number, position, compartment, ok, tt.position, tt.compartment, tt.ok)
+		}
+	}
+}
+
+func TestLayout_Orient(t *testing.T) {
+	tests := []struct {
+		name      string
+		layout    Layout
+		number    int
+		placement models.SeatPlacement
+		direction models.TravelDirection
+	}{
+		{"first lower berth", kupe, 1, "", models.FacingForward},
+		{"first upper berth", kupe, 2, "", models.FacingForward},
+		{"opposite lower berth", kupe, 3, "", models.FacingBackward},
+		{"opposite upper berth", kupe, 4, "", models.FacingBackward},
+		{"sleeper first berth", sv, 17, "", models.FacingForward},
+		{"sleeper second berth", sv, 18, "", models.FacingBackward},
+		{"side berth", platskart, 40, models.SeatWindow, ""},
+		{"front window seat", seating, 1, models.SeatWindow, models.FacingBackward},
+		{"front aisle seat", seating, 2, models.SeatAisle, models.FacingBackward},
+		{"front right window seat", seating, 4, models.SeatWindow, models.FacingBackward},
+		{"last front half row", seating, 29, models.SeatWindow, models.FacingBackward},
+		{"first rear half row", seating, 33, models.SeatWindow, models.FacingForward},
+		{"last seat", seating, 62, models.SeatAisle, models.FacingForward},
+		{"out of range", seating, 63, "", ""},
+	}
+
+	for _, tt := range tests {
+		placement, direction := tt.layout.Orient(tt.number)
+		var gotPlacement models.SeatPlacement
+		var gotDirection models.TravelDirection
+		if placement != nil {
+			gotPlacement = *placement
+		}
+		if direction != nil {
+			gotDirection = *direction
+		}
+		if gotPlacement != tt.placement || gotDirection != tt.direction {
+			t.Errorf("%s: Orient(%d) = %q, %q; want %q, %q",
+				tt.name, tt.number, gotPlacement, gotDirection, tt.placement, tt.direction)
+		}
+	}
+}
+
+func TestLayout_Access(t *testing.T) {
+	accessibleKupe := kupe
+	accessibleKupe.Accessible = true
+	accessibleSeating := seating
+	accessibleSeating.Accessible = true
+
+	tests := []struct {
+		name            string
+		layout          Layout
+		number          int
+		wheelchair      bool
+		reducedMobility bool
+	}{
+		{"not accessible", kupe, 1, false, false},
+		{"first compartment lower berth", accessibleKupe, 3, true, true},
+		{"first compartment upper berth", accessibleKupe, 2, false, false},
+		{"second compartment", accessibleKupe, 5, false, false},
+		{"first open seat", accessibleSeating, 1, true, true},
+		{"first row beyond the wheelchair space", accessibleSeating, 4, false, true},
+		{"second row", accessibleSeating, 5, false, false},
+	}
+
+	for _, tt := range tests {
+		wheelchair, reducedMobility := tt.layout.Access(tt.number)
+		if wheelchair != tt.wheelchair || reducedMobility != tt.reducedMobility {
+			t.Errorf("%s: Access(%d) = %v, %v; want %v, %v",
+				tt.name, tt.number, wheelchair, reducedMobility, tt.wheelchair, tt.reducedMobility)
+		}
+	}
+}
+
+func TestSeats(t *testing.T) {
+	seats := kupe.Seats(7)
+
+	if len(seats) != 36 {
+		t.Fatalf("Expected 36 seats, got %d", len(seats))
+	}
+	last := seats[35]
+	if last.CarriageID != 7 || last.Number != 36 || *last.Compartment != 9 || *last.Position != models.SeatUpper {
+		t.Errorf("Unexpected last seat %+v", last)
+	}
+	if last.Direction == nil || *last.Direction != models.FacingBackward || last.Placement != nil {
+		t.Errorf("Expected the last berth to face backward without a placement, got %+v", last)
+	}
+
+	open := seating.Seats(7)
+	if len(open) != 62 || open[0].Position != nil || open[0].Compartment != nil {
+		t.Errorf("Expected 62 seats without position or compartment, got %d: %+v", len(open), open[0])
+	}
+}
@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	data, err := format("noreply@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Сброс пароля",
+		Body:    "line one\nline two",
+	}, date)
+	if err != nil {
+		t.Fatalf("Failed to format message: %v", err)
+	}
+
+	text := string(data)
+	for _, want := range []string{
+		"From: noreply@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Date: Fri, 16 Oct 2026 12:00:00 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormat_HeaderInjection(t *testing.T) {
+	for _, msg := range []Message{
+		{To: "user@example.com\r\nBcc: other@example.com", Subject: "Hi"},
+		{To: "user@example.com", Subject: "Hi\nBcc: other@example.com"},
+	} {
+		if _, err := format("noreply@example.com", msg, time.Now()); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("Expected ErrInvalidHeader for %+v, got %v", msg, err)
+		}
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewLogMailer(&buf, "noreply@example.com")
+
+	err := mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Verify", Body: "https://example.com/verify"})
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if !strings.Contains(buf.String(), "https://example.com/verify") {
+		t.Errorf("Expected the body to be written, got %q", buf.String())
+	}
+}
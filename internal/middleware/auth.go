@@ -8,9 +8,9 @@ import (
 	"github.com/project13/backend-stealthisproject/pkg/auth"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
-	authService := auth.NewAuthService(nil, jwtSecret)
-	
+// AuthMiddleware accepts requests with a valid access token that hasn't been
+// revoked
+func AuthMiddleware(authService *auth.AuthService, revocations *auth.RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -33,22 +33,29 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if revocations.IsRevoked(claims.JTI) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
 
 		c.Set("user_id", claims.UserID)
-		c.Set("role", claims.Role)
+		c.Set("roles", claims.Roles)
+		c.Set("permissions", claims.Permissions)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// RequirePermission accepts requests whose access token grants the
+// permission; it runs after AuthMiddleware
+func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "ADMIN" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if !auth.HasPermission(c.GetStringSlice("permissions"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission " + permission + " required"})
 			c.Abort()
 			return
 		}
 		c.Next()
 	}
 }
-
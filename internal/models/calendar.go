@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// CalendarExceptionType says whether an exception adds or removes a day
+type CalendarExceptionType string
+
+const (
+	CalendarDayAdded   CalendarExceptionType = "ADDED"
+	CalendarDayRemoved CalendarExceptionType = "REMOVED"
+)
+
+// CalendarException overrides a service calendar on a single date
+type CalendarException struct {
+	CalendarID int64                 `json:"calendarId" db:"calendar_id"`
+	Date       time.Time             `json:"date" db:"date"`
+	Type       CalendarExceptionType `json:"type" db:"type"`
+}
+
+// ServiceCalendar describes the days a route runs on
+type ServiceCalendar struct {
+	ID        int64  `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	Monday    bool   `json:"monday" db:"monday"`
+	Tuesday   bool   `json:"tuesday" db:"tuesday"`
+	Wednesday bool   `json:"wednesday" db:"wednesday"`
+	Thursday  bool   `json:"thursday" db:"thursday"`
+	Friday    bool   `json:"friday" db:"friday"`
+	Saturday  bool   `json:"saturday" db:"saturday"`
+	Sunday    bool   `json:"sunday" db:"sunday"`
+	// StartDate and EndDate bound the days the pattern applies to, both
+	// inclusive; nil leaves that end open
+	StartDate *time.Time `json:"startDate" db:"start_date"`
+	EndDate   *time.Time `json:"endDate" db:"end_date"`
+	// SkipHolidays stops the route on public holidays
+	SkipHolidays bool                `json:"skipHolidays" db:"skip_holidays"`
+	Exceptions   []CalendarException `json:"exceptions"`
+}
+
+// Holiday is a public holiday
+type Holiday struct {
+	Date time.Time `json:"date" db:"date"`
+	Name string    `json:"name" db:"name"`
+}
+
+// RunsOn reports whether service runs on date. An exception for the date
+// decides on its own; otherwise the date has to be within the validity
+// range, not a skipped holiday, and on one of the calendar's weekdays.
+func (c *ServiceCalendar) RunsOn(date time.Time, holiday bool) bool {
+	for _, e := range c.Exceptions {
+		if sameDay(e.Date, date) {
+			return e.Type == CalendarDayAdded
+		}
+	}
+	if c.StartDate != nil && date.Before(*c.StartDate) && !sameDay(date, *c.StartDate) {
+		return false
+	}
+	if c.EndDate != nil && date.After(*c.EndDate) && !sameDay(date, *c.EndDate) {
+		return false
+	}
+	if holiday && c.SkipHolidays {
+		return false
+	}
+
+	switch date.Weekday() {
+	case time.Monday:
+		return c.Monday
+	case time.Tuesday:
+		return c.Tuesday
+	case time.Wednesday:
+		return c.Wednesday
+	case time.Thursday:
+		return c.Thursday
+	case time.Friday:
+		return c.Friday
+	case time.Saturday:
+		return c.Saturday
+	default:
+		return c.Sunday
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestServiceCalendar_RunsOn(t *testing.T) {
+	start, end := date("2030-05-01"), date("2030-05-31")
+	weekdays := ServiceCalendar{
+		Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true,
+		StartDate:    &start,
+		EndDate:      &end,
+		SkipHolidays: true,
+		Exceptions: []CalendarException{
+			{Date: date("2030-05-11"), Type: CalendarDayAdded},
+			{Date: date("2030-05-14"), Type: CalendarDayRemoved},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		date    string
+		holiday bool
+		want    bool
+	}{
+		{"weekday", "2030-05-13", false, true},
+		{"weekend", "2030-05-12", false, false},
+		{"first day of range", "2030-05-01", false, true},
+		{"last day of range", "2030-05-31", false, true},
+		{"before range", "2030-04-30", false, false},
+		{"after range", "2030-06-03", false, false},
+		{"holiday", "2030-05-09", true, false},
+		{"added day", "2030-05-11", false, true},
+		{"removed day", "2030-05-14", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := weekdays.RunsOn(date(tt.date), tt.holiday); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -1,13 +1,71 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
+// User is an account; Roles names its staff roles and is empty for passengers
 type User struct {
-	ID           int64     `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	Role         string    `json:"role" db:"role"`
-	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	ID              int64      `json:"id" db:"id"`
+	Email           string     `json:"email" db:"email"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	Roles           []string   `json:"roles" db:"-"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// TokenPurpose is what a mailed account token can be used for
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "VERIFY_EMAIL"
+	TokenResetPassword TokenPurpose = "RESET_PASSWORD"
+)
+
+// AccountToken is a single-use token mailed to a user, stored as its hash
+type AccountToken struct {
+	ID        int64        `json:"id" db:"id"`
+	UserID    int64        `json:"userId" db:"user_id"`
+	Purpose   TokenPurpose `json:"purpose" db:"purpose"`
+	TokenHash string       `json:"-" db:"token_hash"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time    `json:"expiresAt" db:"expires_at"`
+	UsedAt    *time.Time   `json:"usedAt,omitempty" db:"used_at"`
+}
+
+// Session is a login on one device. Its refresh tokens rotate within it, and
+// revoking it ends every token issued to it.
+type Session struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"userId" db:"user_id"`
+	Device     string     `json:"device" db:"device"`
+	UserAgent  string     `json:"userAgent" db:"user_agent"`
+	IPAddress  string     `json:"ipAddress" db:"ip_address"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	LastSeenAt time.Time  `json:"lastSeenAt" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+// RefreshToken is a stored refresh token of a session, with the access token
+// issued alongside it so that the access token can be revoked with the session
+type RefreshToken struct {
+	ID              int64      `json:"id" db:"id"`
+	SessionID       int64      `json:"sessionId" db:"session_id"`
+	TokenHash       string     `json:"-" db:"token_hash"`
+	AccessJTI       string     `json:"-" db:"access_jti"`
+	AccessExpiresAt time.Time  `json:"-" db:"access_expires_at"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt       time.Time  `json:"expiresAt" db:"expires_at"`
+	UsedAt          *time.Time `json:"usedAt,omitempty" db:"used_at"`
+}
+
+// RevokedToken is the jti of a revoked access token, kept until the token
+// would have expired
+type RevokedToken struct {
+	JTI       string    `json:"jti" db:"jti"`
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
 }
 
 type Passenger struct {
@@ -16,6 +74,7 @@ type Passenger struct {
 	FirstName    string `json:"firstName" db:"first_name"`
 	LastName     string `json:"lastName" db:"last_name"`
 	PassportData string `json:"passportData" db:"passport_data"`
+	IsPrimary    bool   `json:"isPrimary" db:"is_primary"`
 }
 
 type Train struct {
@@ -25,22 +84,63 @@ type Train struct {
 }
 
 type Carriage struct {
-	ID      int64  `json:"id" db:"id"`
-	TrainID int64  `json:"trainId" db:"train_id"`
-	Number  int    `json:"number" db:"number"`
-	Type    string `json:"type" db:"type"`
+	ID      int64 `json:"id" db:"id"`
+	TrainID int64 `json:"trainId" db:"train_id"`
+	Number  int   `json:"number" db:"number"`
+	TypeID  int64 `json:"typeId" db:"type_id"`
+	// Type is the carriage type the carriage is built from, loaded with it
+	Type CarriageType `json:"type"`
+}
+
+// CarriageType is a template for carriages: the layout their seats are
+// generated from, the fare multiplier of the class and its amenities. Seats
+// are numbered compartment by compartment, then the side berths, then open
+// seats without a compartment.
+type CarriageType struct {
+	ID                  int64    `json:"id" db:"id"`
+	Name                string   `json:"name" db:"name"`
+	Compartments        int      `json:"compartments" db:"compartments"`
+	SeatsPerCompartment int      `json:"seatsPerCompartment" db:"seats_per_compartment"`
+	SideSeats           int      `json:"sideSeats" db:"side_seats"`
+	OpenSeats           int      `json:"openSeats" db:"open_seats"`
+	FareMultiplier      float64  `json:"fareMultiplier" db:"fare_multiplier"`
+	Amenities           []string `json:"amenities" db:"amenities"`
+}
+
+// AmenityWheelchairAccess marks a carriage type with seats for passengers
+// with reduced mobility and room for a wheelchair
+const AmenityWheelchairAccess = "WHEELCHAIR_ACCESS"
+
+// HasAmenity reports whether the type lists amenity, ignoring case
+func (t CarriageType) HasAmenity(amenity string) bool {
+	for _, a := range t.Amenities {
+		if strings.EqualFold(a, amenity) {
+			return true
+		}
+	}
+	return false
 }
 
+// Seat is a seat or berth. Attributes that don't apply to the carriage
+// class, such as the compartment of an open-plan carriage, are nil.
 type Seat struct {
-	ID         int64 `json:"id" db:"id"`
-	CarriageID int64 `json:"carriageId" db:"carriage_id"`
-	Number     int   `json:"number" db:"number"`
+	ID              int64            `json:"id" db:"id"`
+	CarriageID      int64            `json:"carriageId" db:"carriage_id"`
+	Number          int              `json:"number" db:"number"`
+	Position        *SeatPosition    `json:"position,omitempty" db:"position"`
+	Compartment     *int             `json:"compartment,omitempty" db:"compartment"`
+	Placement       *SeatPlacement   `json:"placement,omitempty" db:"placement"`
+	Direction       *TravelDirection `json:"direction,omitempty" db:"direction"`
+	WheelchairSpace bool             `json:"wheelchairSpace" db:"wheelchair_space"`
+	ReducedMobility bool             `json:"reducedMobility" db:"reduced_mobility"`
 }
 
 type Station struct {
 	ID   int64  `json:"id" db:"id"`
 	Name string `json:"name" db:"name"`
 	City string `json:"city" db:"city"`
+	// TimeZone is the IANA zone the station's timetable is given in
+	TimeZone string `json:"timeZone" db:"time_zone"`
 }
 
 type Route struct {
@@ -48,6 +148,8 @@ type Route struct {
 	Name   string  `json:"name" db:"name"`
 	TrainID int64  `json:"trainId" db:"train_id"`
 	Price  float64 `json:"price" db:"price"`
+	// CalendarID is the service calendar of the route; nil means every day
+	CalendarID *int64 `json:"calendarId" db:"calendar_id"`
 }
 
 type RouteStation struct {
@@ -55,7 +157,15 @@ type RouteStation struct {
 	StationID    int64     `json:"stationId" db:"station_id"`
 	ArrivalTime  *time.Time `json:"arrivalTime" db:"arrival_time"`
 	DepartureTime *time.Time `json:"departureTime" db:"departure_time"`
+	// ArrivalDayOffset and DepartureDayOffset count the days between the
+	// train leaving its first station and the arrival or departure here
+	ArrivalDayOffset   int `json:"arrivalDayOffset" db:"arrival_day_offset"`
+	DepartureDayOffset int `json:"departureDayOffset" db:"departure_day_offset"`
 	StopOrder    int       `json:"stopOrder" db:"stop_order"`
+	// TimeZone is the zone of the station, in which the times are local
+	TimeZone string `json:"timeZone" db:"time_zone"`
+	// City is the city of the station, loaded with the stop for searches
+	City string `json:"city" db:"city"`
 }
 
 type Order struct {
@@ -63,19 +173,38 @@ type Order struct {
 	UserID     int64     `json:"userId" db:"user_id"`
 	RouteID    *int64    `json:"routeId,omitempty" db:"route_id"`
 	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
-	Status     string    `json:"status" db:"status"`
+	Status     OrderStatus `json:"status" db:"status"`
 	TotalAmount float64  `json:"totalAmount" db:"total_amount"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
 	Tickets    []Ticket  `json:"tickets,omitempty"`
 }
 
 type Ticket struct {
 	ID           int64     `json:"id" db:"id"`
 	OrderID      int64     `json:"orderId" db:"order_id"`
+	RouteID      *int64    `json:"routeId,omitempty" db:"route_id"`
 	SeatID       *int64    `json:"seatId" db:"seat_id"`
 	PassengerID  *int64    `json:"passengerId" db:"passenger_id"`
 	DepartureDate time.Time `json:"departureDate" db:"departure_date"`
+	FromStationID *int64   `json:"fromStationId" db:"from_station_id"`
+	ToStationID  *int64    `json:"toStationId" db:"to_station_id"`
+	// FromStop and ToStop are the stop orders of the boarding and alighting
+	// stations; the ticket occupies its seat on the legs in between
+	FromStop     *int      `json:"-" db:"from_stop_order"`
+	ToStop       *int      `json:"-" db:"to_stop_order"`
+	// DepartsAt and ArrivesAt are when the train leaves the boarding station
+	// and reaches the alighting one
+	DepartsAt    *time.Time `json:"departsAt" db:"departs_at"`
+	ArrivesAt    *time.Time `json:"arrivesAt" db:"arrives_at"`
 	Price        float64   `json:"price" db:"price"`
 	TicketNumber string    `json:"ticketNumber" db:"ticket_number"`
-	Status       string    `json:"status" db:"status"`
+	Status       TicketStatus `json:"status" db:"status"`
+}
+
+// Segment is the part of a route between two stops, given by their stop
+// orders
+type Segment struct {
+	FromStop int
+	ToStop   int
 }
 
@@ -0,0 +1,27 @@
+package models
+
+// Permissions staff roles grant. Users without roles are passengers and hold
+// none of them.
+const (
+	PermRoutesRead     = "routes:read"
+	PermRoutesWrite    = "routes:write"
+	PermStationsRead   = "stations:read"
+	PermStationsWrite  = "stations:write"
+	PermTrainsRead     = "trains:read"
+	PermTrainsWrite    = "trains:write"
+	PermCalendarsRead  = "calendars:read"
+	PermCalendarsWrite = "calendars:write"
+	PermOrdersRead     = "orders:read"
+	PermOrdersWrite    = "orders:write"
+	PermOrdersRefund   = "orders:refund"
+	PermUsersRead      = "users:read"
+	PermUsersWrite     = "users:write"
+)
+
+// Role is a named set of permissions assigned to staff users
+type Role struct {
+	ID          int64    `json:"id" db:"id"`
+	Name        string   `json:"name" db:"name"`
+	Description string   `json:"description" db:"description"`
+	Permissions []string `json:"permissions" db:"-"`
+}
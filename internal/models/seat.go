@@ -0,0 +1,89 @@
+package models
+
+// SeatPosition is where a berth or seat sits within its compartment
+type SeatPosition string
+
+const (
+	SeatLower     SeatPosition = "LOWER"
+	SeatUpper     SeatPosition = "UPPER"
+	SeatSideLower SeatPosition = "SIDE_LOWER"
+	SeatSideUpper SeatPosition = "SIDE_UPPER"
+)
+
+// SeatPlacement tells a window seat from an aisle seat
+type SeatPlacement string
+
+const (
+	SeatWindow SeatPlacement = "WINDOW"
+	SeatAisle  SeatPlacement = "AISLE"
+)
+
+// TravelDirection is which way a seat faces relative to the train's movement
+type TravelDirection string
+
+const (
+	FacingForward  TravelDirection = "FORWARD"
+	FacingBackward TravelDirection = "BACKWARD"
+)
+
+// ValidSeatPosition reports whether p is a known seat position
+func ValidSeatPosition(p SeatPosition) bool {
+	switch p {
+	case SeatLower, SeatUpper, SeatSideLower, SeatSideUpper:
+		return true
+	}
+	return false
+}
+
+// ValidSeatPlacement reports whether p is a known seat placement
+func ValidSeatPlacement(p SeatPlacement) bool {
+	return p == SeatWindow || p == SeatAisle
+}
+
+// ValidTravelDirection reports whether d is a known travel direction
+func ValidTravelDirection(d TravelDirection) bool {
+	return d == FacingForward || d == FacingBackward
+}
+
+// SeatFilter narrows seats down by their attributes. Zero fields don't
+// filter; Positions matches any of the listed positions.
+type SeatFilter struct {
+	Positions       []SeatPosition
+	Placement       SeatPlacement
+	Direction       TravelDirection
+	WheelchairSpace bool
+	ReducedMobility bool
+}
+
+// Matches reports whether the seat has every attribute the filter asks for.
+// Seats whose attribute is unknown don't match a filter on it.
+func (f SeatFilter) Matches(seat *Seat) bool {
+	if len(f.Positions) > 0 {
+		if seat.Position == nil {
+			return false
+		}
+		found := false
+		for _, p := range f.Positions {
+			if *seat.Position == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Placement != "" && (seat.Placement == nil || *seat.Placement != f.Placement) {
+		return false
+	}
+	if f.Direction != "" && (seat.Direction == nil || *seat.Direction != f.Direction) {
+		return false
+	}
+	if f.WheelchairSpace && !seat.WheelchairSpace {
+		return false
+	}
+	if f.ReducedMobility && !seat.ReducedMobility {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package models
+
+import "testing"
+
+func TestSeatFilter_Matches(t *testing.T) {
+	lower := SeatLower
+	sideUpper := SeatSideUpper
+	window := SeatWindow
+
+	tests := []struct {
+		name   string
+		filter SeatFilter
+		seat   Seat
+		want   bool
+	}{
+		{"empty filter", SeatFilter{}, Seat{}, true},
+		{"position matches", SeatFilter{Positions: []SeatPosition{SeatLower, SeatSideLower}}, Seat{Position: &lower}, true},
+		{"position differs", SeatFilter{Positions: []SeatPosition{SeatLower, SeatSideLower}}, Seat{Position: &sideUpper}, false},
+		{"position unknown", SeatFilter{Positions: []SeatPosition{SeatLower}}, Seat{}, false},
+		{"placement matches", SeatFilter{Placement: SeatWindow}, Seat{Placement: &window}, true},
+		{"direction unknown", SeatFilter{Direction: FacingForward}, Seat{}, false},
+		{"wheelchair space", SeatFilter{WheelchairSpace: true}, Seat{WheelchairSpace: true}, true},
+		{"no wheelchair space", SeatFilter{WheelchairSpace: true}, Seat{Position: &lower}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Matches(&tt.seat); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+const (
+	OrderPending           OrderStatus = "PENDING"
+	OrderPaid              OrderStatus = "PAID"
+	OrderPartiallyRefunded OrderStatus = "PARTIALLY_REFUNDED"
+	OrderRefunded          OrderStatus = "REFUNDED"
+	OrderExpired           OrderStatus = "EXPIRED"
+	OrderCancelled         OrderStatus = "CANCELLED"
+)
+
+// orderTransitions lists the states each order state may move to. Refunds
+// can be made ticket by ticket, so a partially refunded order may be
+// partially refunded again.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:           {OrderPaid, OrderExpired, OrderCancelled},
+	OrderPaid:              {OrderPartiallyRefunded, OrderRefunded},
+	OrderPartiallyRefunded: {OrderPartiallyRefunded, OrderRefunded},
+}
+
+// CanTransitionTo reports whether an order may move from s to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when an order is asked to make a move the
+// state machine doesn't allow
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change order status from %s to %s", e.From, e.To)
+}
+
+// TicketStatus is the state of a single ticket. HELD and ACTIVE tickets
+// occupy their seat; the others release it.
+type TicketStatus string
+
+const (
+	TicketHeld      TicketStatus = "HELD"
+	TicketActive    TicketStatus = "ACTIVE"
+	TicketExpired   TicketStatus = "EXPIRED"
+	TicketCancelled TicketStatus = "CANCELLED"
+	TicketRefunded  TicketStatus = "REFUNDED"
+)
+
+// OrderStatusChange is an entry of an order's status history. ChangedBy is
+// nil for changes made by the system, e.g. when a seat hold expires.
+type OrderStatusChange struct {
+	ID         int64        `json:"id" db:"id"`
+	OrderID    int64        `json:"orderId" db:"order_id"`
+	FromStatus *OrderStatus `json:"fromStatus" db:"from_status"`
+	ToStatus   OrderStatus  `json:"toStatus" db:"to_status"`
+	ChangedBy  *int64       `json:"changedBy" db:"changed_by"`
+	ChangedAt  time.Time    `json:"changedAt" db:"changed_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "testing"
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from OrderStatus
+		to   OrderStatus
+		want bool
+	}{
+		{OrderPending, OrderPaid, true},
+		{OrderPending, OrderExpired, true},
+		{OrderPending, OrderCancelled, true},
+		{OrderPaid, OrderPartiallyRefunded, true},
+		{OrderPaid, OrderRefunded, true},
+		{OrderPartiallyRefunded, OrderPartiallyRefunded, true},
+		{OrderPartiallyRefunded, OrderRefunded, true},
+		{OrderPaid, OrderPaid, false},
+		{OrderPaid, OrderCancelled, false},
+		{OrderCancelled, OrderPaid, false},
+		{OrderExpired, OrderPaid, false},
+		{OrderRefunded, OrderPaid, false},
+		{OrderPending, OrderRefunded, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Category is the passenger fare category
+type Category string
+
+const (
+	CategoryAdult   Category = "ADULT"
+	CategoryChild   Category = "CHILD"
+	CategoryStudent Category = "STUDENT"
+	CategorySenior  Category = "SENIOR"
+)
+
+var categoryDiscounts = map[Category]float64{
+	CategoryAdult:   1.0,
+	CategoryChild:   0.5,
+	CategoryStudent: 0.8,
+	CategorySenior:  0.7,
+}
+
+var ErrInvalidSegment = errors.New("invalid route segment")
+
+// ErrNoPrice is returned for a route without a positive fare, so that a
+// missing price never sells free tickets
+var ErrNoPrice = errors.New("route has no fare")
+
+// ParseCategory validates a passenger category; an empty string means adult
+func ParseCategory(s string) (Category, error) {
+	if s == "" {
+		return CategoryAdult, nil
+	}
+	category := Category(strings.ToUpper(s))
+	if _, ok := categoryDiscounts[category]; !ok {
+		return "", fmt.Errorf("unknown passenger category %q", s)
+	}
+	return category, nil
+}
+
+// Fare describes what is being priced. Route.Price is the adult fare in the
+// base class for the whole route; a segment costs its share of the route's
+// legs (stop-to-stop hops). Multiplier is the fare multiplier of the carriage
+// type; zero prices the base class.
+type Fare struct {
+	RoutePrice float64
+	Multiplier float64
+	Legs       int
+	TotalLegs  int
+	Category   Category
+}
+
+// Calculate returns the fare rounded to kopecks
+func Calculate(f Fare) (float64, error) {
+	if f.RoutePrice <= 0 {
+		return 0, ErrNoPrice
+	}
+	if f.TotalLegs <= 0 || f.Legs <= 0 || f.Legs > f.TotalLegs {
+		return 0, ErrInvalidSegment
+	}
+
+	category := f.Category
+	if category == "" {
+		category = CategoryAdult
+	}
+	discount, ok := categoryDiscounts[category]
+	if !ok {
+		return 0, fmt.Errorf("unknown passenger category %q", f.Category)
+	}
+
+	price := f.RoutePrice * float64(f.Legs) / float64(f.TotalLegs)
+	if f.Multiplier > 0 {
+		price *= f.Multiplier
+	}
+	price *= discount
+	return Round(price), nil
+}
+
+// Round rounds an amount to kopecks
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Matches reports whether a client-side price agrees with the computed one
+func Matches(expected, actual float64) bool {
+	return math.Abs(expected-actual) < 0.005
+}
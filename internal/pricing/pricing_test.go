@@ -0,0 +1,74 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name string
+		fare Fare
+		want float64
+	}{
+		{"adult platskart whole route", Fare{RoutePrice: 28, Multiplier: 1, Legs: 1, TotalLegs: 1}, 28},
+		{"adult kupe whole route", Fare{RoutePrice: 28, Multiplier: 1.5, Legs: 1, TotalLegs: 1}, 42},
+		{"adult sv whole route", Fare{RoutePrice: 28, Multiplier: 2.5, Legs: 1, TotalLegs: 1}, 70},
+		{"child kupe", Fare{RoutePrice: 28, Multiplier: 1.5, Legs: 1, TotalLegs: 1, Category: CategoryChild}, 21},
+		{"half the route", Fare{RoutePrice: 28, Multiplier: 1, Legs: 1, TotalLegs: 2}, 14},
+		{"rounded to kopecks", Fare{RoutePrice: 20, Multiplier: 1, Legs: 1, TotalLegs: 3}, 6.67},
+		{"no carriage type", Fare{RoutePrice: 20, Legs: 1, TotalLegs: 1}, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Calculate(tt.fare)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %.2f, got %.2f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCalculate_InvalidSegment(t *testing.T) {
+	_, err := Calculate(Fare{RoutePrice: 28, Legs: 3, TotalLegs: 2})
+	if !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("Expected ErrInvalidSegment, got %v", err)
+	}
+}
+
+func TestCalculate_NoPrice(t *testing.T) {
+	for _, price := range []float64{0, -5} {
+		if _, err := Calculate(Fare{RoutePrice: price, Legs: 1, TotalLegs: 1}); !errors.Is(err, ErrNoPrice) {
+			t.Errorf("Expected ErrNoPrice for %v, got %v", price, err)
+		}
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	category, err := ParseCategory("")
+	if err != nil || category != CategoryAdult {
+		t.Errorf("Expected empty category to default to adult, got %q, %v", category, err)
+	}
+
+	category, err = ParseCategory("child")
+	if err != nil || category != CategoryChild {
+		t.Errorf("Expected CHILD, got %q, %v", category, err)
+	}
+
+	if _, err := ParseCategory("VIP"); err == nil {
+		t.Error("Expected error for unknown category")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	if !Matches(42.0, 42.001) {
+		t.Error("Expected prices within half a kopeck to match")
+	}
+	if Matches(0.01, 42) {
+		t.Error("Expected different prices not to match")
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+type accountTokenRepository struct {
+	db queryer
+}
+
+func NewAccountTokenRepository(db *sql.DB) AccountTokenRepository {
+	return &accountTokenRepository{db: db}
+}
+
+func (r *accountTokenRepository) Create(ctx context.Context, token *models.AccountToken) error {
+	query := `INSERT INTO account_tokens (user_id, purpose, token_hash, expires_at)
+	          VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *accountTokenRepository) GetByHash(ctx context.Context, purpose models.TokenPurpose, hash string) (*models.AccountToken, error) {
+	token := &models.AccountToken{}
+	query := `SELECT id, user_id, purpose, token_hash, created_at, expires_at, used_at
+	          FROM account_tokens WHERE purpose = $1 AND token_hash = $2`
+	err := r.db.QueryRowContext(ctx, query, purpose, hash).Scan(&token.ID, &token.UserID, &token.Purpose,
+		&token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// MarkUsed spends the token; it reports false if the token was already used
+func (r *accountTokenRepository) MarkUsed(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE account_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// LastCreatedAt is when the user's latest token of the purpose was created,
+// zero if there is none
+func (r *accountTokenRepository) LastCreatedAt(ctx context.Context, userID int64, purpose models.TokenPurpose) (time.Time, error) {
+	var createdAt sql.NullTime
+	query := `SELECT MAX(created_at) FROM account_tokens WHERE user_id = $1 AND purpose = $2`
+	err := r.db.QueryRowContext(ctx, query, userID, purpose).Scan(&createdAt)
+	return createdAt.Time, err
+}
+
+// InvalidateByUserID spends the user's unused tokens of the purpose, so only
+// the most recently mailed one works
+func (r *accountTokenRepository) InvalidateByUserID(ctx context.Context, userID int64, purpose models.TokenPurpose) error {
+	query := `UPDATE account_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID, purpose)
+	return err
+}
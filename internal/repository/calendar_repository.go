@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+type calendarRepository struct {
+	db queryer
+}
+
+func NewCalendarRepository(db *sql.DB) CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+const calendarColumns = `id, name, monday, tuesday, wednesday, thursday, friday, saturday, sunday, start_date, end_date, skip_holidays`
+
+func scanCalendar(row interface{ Scan(...interface{}) error }, cal *models.ServiceCalendar) error {
+	return row.Scan(&cal.ID, &cal.Name, &cal.Monday, &cal.Tuesday, &cal.Wednesday, &cal.Thursday,
+		&cal.Friday, &cal.Saturday, &cal.Sunday, &cal.StartDate, &cal.EndDate, &cal.SkipHolidays)
+}
+
+func (r *calendarRepository) Create(ctx context.Context, cal *models.ServiceCalendar) error {
+	query := `INSERT INTO service_calendars (name, monday, tuesday, wednesday, thursday, friday, saturday, sunday, start_date, end_date, skip_holidays)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, cal.Name, cal.Monday, cal.Tuesday, cal.Wednesday, cal.Thursday,
+		cal.Friday, cal.Saturday, cal.Sunday, cal.StartDate, cal.EndDate, cal.SkipHolidays).Scan(&cal.ID)
+}
+
+// GetByID returns the calendar with its exceptions
+func (r *calendarRepository) GetByID(ctx context.Context, id int64) (*models.ServiceCalendar, error) {
+	cal := &models.ServiceCalendar{}
+	query := `SELECT ` + calendarColumns + ` FROM service_calendars WHERE id = $1`
+	err := scanCalendar(r.db.QueryRowContext(ctx, query, id), cal)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cal.Exceptions, err = r.exceptions(ctx, `WHERE calendar_id = $1`, id)
+	return cal, err
+}
+
+// GetAll returns every calendar with its exceptions
+func (r *calendarRepository) GetAll(ctx context.Context) ([]models.ServiceCalendar, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+calendarColumns+` FROM service_calendars ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calendars []models.ServiceCalendar
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var cal models.ServiceCalendar
+		if err := scanCalendar(rows, &cal); err != nil {
+			return nil, err
+		}
+		byID[cal.ID] = len(calendars)
+		calendars = append(calendars, cal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	exceptions, err := r.exceptions(ctx, ``)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range exceptions {
+		if i, ok := byID[e.CalendarID]; ok {
+			calendars[i].Exceptions = append(calendars[i].Exceptions, e)
+		}
+	}
+	return calendars, nil
+}
+
+func (r *calendarRepository) exceptions(ctx context.Context, where string, args ...interface{}) ([]models.CalendarException, error) {
+	query := `SELECT calendar_id, date, type FROM calendar_exceptions ` + where + ` ORDER BY calendar_id, date`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []models.CalendarException
+	for rows.Next() {
+		var e models.CalendarException
+		if err := rows.Scan(&e.CalendarID, &e.Date, &e.Type); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+func (r *calendarRepository) Update(ctx context.Context, cal *models.ServiceCalendar) error {
+	query := `UPDATE service_calendars SET name = $1, monday = $2, tuesday = $3, wednesday = $4, thursday = $5,
+	          friday = $6, saturday = $7, sunday = $8, start_date = $9, end_date = $10, skip_holidays = $11
+	          WHERE id = $12`
+	_, err := r.db.ExecContext(ctx, query, cal.Name, cal.Monday, cal.Tuesday, cal.Wednesday, cal.Thursday,
+		cal.Friday, cal.Saturday, cal.Sunday, cal.StartDate, cal.EndDate, cal.SkipHolidays, cal.ID)
+	return err
+}
+
+func (r *calendarRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM service_calendars WHERE id = $1`, id)
+	if isConstraintViolation(err, foreignKeyViolation, "fk_routes_calendar") {
+		return ErrCalendarInUse
+	}
+	return err
+}
+
+// SetException adds or replaces the exception of the calendar on its date
+func (r *calendarRepository) SetException(ctx context.Context, e *models.CalendarException) error {
+	query := `INSERT INTO calendar_exceptions (calendar_id, date, type) VALUES ($1, $2, $3)
+	          ON CONFLICT (calendar_id, date) DO UPDATE SET type = EXCLUDED.type`
+	_, err := r.db.ExecContext(ctx, query, e.CalendarID, e.Date, e.Type)
+	return err
+}
+
+// DeleteException removes the exception on date and reports whether there
+// was one
+func (r *calendarRepository) DeleteException(ctx context.Context, calendarID int64, date time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM calendar_exceptions WHERE calendar_id = $1 AND date = $2`, calendarID, date)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *calendarRepository) GetHolidays(ctx context.Context) ([]models.Holiday, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT date, name FROM holidays ORDER BY date`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holidays []models.Holiday
+	for rows.Next() {
+		var h models.Holiday
+		if err := rows.Scan(&h.Date, &h.Name); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, h)
+	}
+	return holidays, rows.Err()
+}
+
+func (r *calendarRepository) IsHoliday(ctx context.Context, date time.Time) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM holidays WHERE date = $1)`, date).Scan(&exists)
+	return exists, err
+}
+
+// SetHoliday adds a holiday or renames the one on the same date
+func (r *calendarRepository) SetHoliday(ctx context.Context, h *models.Holiday) error {
+	query := `INSERT INTO holidays (date, name) VALUES ($1, $2) ON CONFLICT (date) DO UPDATE SET name = EXCLUDED.name`
+	_, err := r.db.ExecContext(ctx, query, h.Date, h.Name)
+	return err
+}
+
+// DeleteHoliday removes the holiday on date and reports whether there was one
+func (r *calendarRepository) DeleteHoliday(ctx context.Context, date time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM holidays WHERE date = $1`, date)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
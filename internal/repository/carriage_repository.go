@@ -1,36 +1,58 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type carriageRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewCarriageRepository(db *sql.DB) CarriageRepository {
 	return &carriageRepository{db: db}
 }
 
-func (r *carriageRepository) Create(carriage *models.Carriage) error {
-	query := `INSERT INTO carriages (train_id, number, type) VALUES ($1, $2, $3) RETURNING id`
-	return r.db.QueryRow(query, carriage.TrainID, carriage.Number, carriage.Type).Scan(&carriage.ID)
+// carriageColumns selects a carriage with its type from carriages c joined
+// with carriage_types t
+const carriageColumns = `c.id, c.train_id, c.number, c.type_id, t.id, t.name, t.compartments, t.seats_per_compartment,
+	t.side_seats, t.open_seats, t.fare_multiplier, t.amenities`
+
+func scanCarriage(row interface{ Scan(...interface{}) error }, carriage *models.Carriage) error {
+	t := &carriage.Type
+	return row.Scan(&carriage.ID, &carriage.TrainID, &carriage.Number, &carriage.TypeID, &t.ID, &t.Name,
+		&t.Compartments, &t.SeatsPerCompartment, &t.SideSeats, &t.OpenSeats, &t.FareMultiplier, pq.Array(&t.Amenities))
+}
+
+func (r *carriageRepository) Create(ctx context.Context, carriage *models.Carriage) error {
+	query := `INSERT INTO carriages (train_id, number, type_id) VALUES ($1, $2, $3) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, carriage.TrainID, carriage.Number, carriage.TypeID).Scan(&carriage.ID)
+	if isConstraintViolation(err, uniqueViolation, "uq_carriages_train_number") {
+		return ErrCarriageNumberTaken
+	}
+	return err
 }
 
-func (r *carriageRepository) GetByID(id int64) (*models.Carriage, error) {
+func (r *carriageRepository) GetByID(ctx context.Context, id int64) (*models.Carriage, error) {
 	carriage := &models.Carriage{}
-	query := `SELECT id, train_id, number, type FROM carriages WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&carriage.ID, &carriage.TrainID, &carriage.Number, &carriage.Type)
+	query := `SELECT ` + carriageColumns + `
+	          FROM carriages c INNER JOIN carriage_types t ON t.id = c.type_id
+	          WHERE c.id = $1`
+	err := scanCarriage(r.db.QueryRowContext(ctx, query, id), carriage)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return carriage, err
 }
 
-func (r *carriageRepository) GetByTrainID(trainID int64) ([]models.Carriage, error) {
-	query := `SELECT id, train_id, number, type FROM carriages WHERE train_id = $1 ORDER BY number`
-	rows, err := r.db.Query(query, trainID)
+func (r *carriageRepository) GetByTrainID(ctx context.Context, trainID int64) ([]models.Carriage, error) {
+	query := `SELECT ` + carriageColumns + `
+	          FROM carriages c INNER JOIN carriage_types t ON t.id = c.type_id
+	          WHERE c.train_id = $1 ORDER BY c.number`
+	rows, err := r.db.QueryContext(ctx, query, trainID)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +61,7 @@ func (r *carriageRepository) GetByTrainID(trainID int64) ([]models.Carriage, err
 	var carriages []models.Carriage
 	for rows.Next() {
 		var carriage models.Carriage
-		if err := rows.Scan(&carriage.ID, &carriage.TrainID, &carriage.Number, &carriage.Type); err != nil {
+		if err := scanCarriage(rows, &carriage); err != nil {
 			return nil, err
 		}
 		carriages = append(carriages, carriage)
@@ -47,3 +69,33 @@ func (r *carriageRepository) GetByTrainID(trainID int64) ([]models.Carriage, err
 	return carriages, rows.Err()
 }
 
+func (r *carriageRepository) Update(ctx context.Context, carriage *models.Carriage) error {
+	query := `UPDATE carriages SET number = $1, type_id = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, carriage.Number, carriage.TypeID, carriage.ID)
+	if isConstraintViolation(err, uniqueViolation, "uq_carriages_train_number") {
+		return ErrCarriageNumberTaken
+	}
+	return err
+}
+
+// Delete removes the carriage with its seats. Carriages whose seats have
+// tickets, in any status, are kept for the tickets' sake.
+func (r *carriageRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM carriages WHERE id = $1`, id)
+	if isConstraintViolation(err, foreignKeyViolation, "tickets_seat_id_fkey") {
+		return ErrCarriageInUse
+	}
+	return err
+}
+
+// HasSoldTickets reports whether any seat of the carriage has a held or sold
+// ticket
+func (r *carriageRepository) HasSoldTickets(ctx context.Context, id int64) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM tickets t
+	              INNER JOIN seats s ON t.seat_id = s.id
+	              WHERE s.carriage_id = $1 AND t.status IN ('HELD', 'ACTIVE'))`
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&exists)
+	return exists, err
+}
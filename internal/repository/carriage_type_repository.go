@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+type carriageTypeRepository struct {
+	db queryer
+}
+
+func NewCarriageTypeRepository(db *sql.DB) CarriageTypeRepository {
+	return &carriageTypeRepository{db: db}
+}
+
+const carriageTypeColumns = `id, name, compartments, seats_per_compartment, side_seats, open_seats, fare_multiplier, amenities`
+
+func scanCarriageType(row interface{ Scan(...interface{}) error }, t *models.CarriageType) error {
+	return row.Scan(&t.ID, &t.Name, &t.Compartments, &t.SeatsPerCompartment, &t.SideSeats, &t.OpenSeats,
+		&t.FareMultiplier, pq.Array(&t.Amenities))
+}
+
+func (r *carriageTypeRepository) Create(ctx context.Context, t *models.CarriageType) error {
+	query := `INSERT INTO carriage_types (name, compartments, seats_per_compartment, side_seats, open_seats, fare_multiplier, amenities)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, t.Name, t.Compartments, t.SeatsPerCompartment, t.SideSeats, t.OpenSeats,
+		t.FareMultiplier, pq.Array(t.Amenities)).Scan(&t.ID)
+	if isConstraintViolation(err, uniqueViolation, "uq_carriage_types_name") {
+		return ErrCarriageTypeNameTaken
+	}
+	return err
+}
+
+func (r *carriageTypeRepository) GetByID(ctx context.Context, id int64) (*models.CarriageType, error) {
+	t := &models.CarriageType{}
+	query := `SELECT ` + carriageTypeColumns + ` FROM carriage_types WHERE id = $1`
+	err := scanCarriageType(r.db.QueryRowContext(ctx, query, id), t)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// GetByName finds a carriage type by its name, ignoring case
+func (r *carriageTypeRepository) GetByName(ctx context.Context, name string) (*models.CarriageType, error) {
+	t := &models.CarriageType{}
+	query := `SELECT ` + carriageTypeColumns + ` FROM carriage_types WHERE LOWER(name) = LOWER($1)`
+	err := scanCarriageType(r.db.QueryRowContext(ctx, query, name), t)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (r *carriageTypeRepository) GetAll(ctx context.Context) ([]models.CarriageType, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+carriageTypeColumns+` FROM carriage_types ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []models.CarriageType
+	for rows.Next() {
+		var t models.CarriageType
+		if err := scanCarriageType(rows, &t); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+func (r *carriageTypeRepository) Update(ctx context.Context, t *models.CarriageType) error {
+	query := `UPDATE carriage_types
+	          SET name = $1, compartments = $2, seats_per_compartment = $3, side_seats = $4, open_seats = $5,
+	              fare_multiplier = $6, amenities = $7
+	          WHERE id = $8`
+	_, err := r.db.ExecContext(ctx, query, t.Name, t.Compartments, t.SeatsPerCompartment, t.SideSeats, t.OpenSeats,
+		t.FareMultiplier, pq.Array(t.Amenities), t.ID)
+	if isConstraintViolation(err, uniqueViolation, "uq_carriage_types_name") {
+		return ErrCarriageTypeNameTaken
+	}
+	return err
+}
+
+func (r *carriageTypeRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM carriage_types WHERE id = $1`, id)
+	if isConstraintViolation(err, foreignKeyViolation, "fk_carriages_type") {
+		return ErrCarriageTypeInUse
+	}
+	return err
+}
+
+// HasCarriages reports whether any carriage is built from the type
+func (r *carriageTypeRepository) HasCarriages(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM carriages WHERE type_id = $1)`, id).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrSeatUnavailable is returned when a seat already has an active ticket
+// for an overlapping segment of the same route and departure date
+var ErrSeatUnavailable = errors.New("seat is not available")
+
+// ErrCalendarInUse is returned when deleting a service calendar that routes
+// still run on
+var ErrCalendarInUse = errors.New("calendar is used by routes")
+
+// ErrStationInUse is returned when deleting a station that routes call at or
+// tickets refer to
+var ErrStationInUse = errors.New("station is in use")
+
+// ErrCarriageInUse is returned when removing a carriage or its seats while
+// tickets refer to them
+var ErrCarriageInUse = errors.New("carriage has tickets")
+
+// ErrCarriageNumberTaken is returned when a train already has a carriage with
+// the number
+var ErrCarriageNumberTaken = errors.New("carriage number is taken")
+
+// ErrCarriageTypeInUse is returned when deleting a carriage type that
+// carriages are built from
+var ErrCarriageTypeInUse = errors.New("carriage type is used by carriages")
+
+// ErrCarriageTypeNameTaken is returned when another carriage type already has
+// the name
+var ErrCarriageTypeNameTaken = errors.New("carriage type name is taken")
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	exclusionViolation  = "23P01"
+)
+
+// isConstraintViolation reports whether err is a violation of the named
+// constraint with the given SQLSTATE code
+func isConstraintViolation(err error, code, constraint string) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code) == code && pqErr.Constraint == constraint
+	}
+	return false
+}
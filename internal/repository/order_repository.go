@@ -1,40 +1,74 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type orderRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewOrderRepository(db *sql.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
-func (r *orderRepository) Create(order *models.Order) error {
-	query := `INSERT INTO orders (user_id, route_id, status, total_amount) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
-	return r.db.QueryRow(query, order.UserID, order.RouteID, order.Status, order.TotalAmount).Scan(&order.ID, &order.CreatedAt)
-}
+const orderColumns = `id, user_id, route_id, created_at, status, total_amount, expires_at`
 
-func (r *orderRepository) GetByID(id int64) (*models.Order, error) {
-	order := &models.Order{}
-	query := `SELECT id, user_id, route_id, created_at, status, total_amount FROM orders WHERE id = $1`
+func scanOrder(row interface{ Scan(...interface{}) error }, order *models.Order) error {
 	var routeID sql.NullInt64
-	err := r.db.QueryRow(query, id).Scan(&order.ID, &order.UserID, &routeID, &order.CreatedAt, &order.Status, &order.TotalAmount)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	var expiresAt sql.NullTime
+	if err := row.Scan(&order.ID, &order.UserID, &routeID, &order.CreatedAt, &order.Status, &order.TotalAmount, &expiresAt); err != nil {
+		return err
 	}
 	if routeID.Valid {
 		order.RouteID = &routeID.Int64
 	}
+	if expiresAt.Valid {
+		order.ExpiresAt = &expiresAt.Time
+	}
+	return nil
+}
+
+// Create inserts the order and starts its status history with the user who
+// created it, the owner or staff booking on their behalf
+func (r *orderRepository) Create(ctx context.Context, order *models.Order, createdBy int64) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO orders (user_id, route_id, status, total_amount, expires_at) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, status
+		), history AS (
+			INSERT INTO order_status_history (order_id, to_status, changed_by, changed_at)
+			SELECT id, status, $6, created_at FROM inserted
+		)
+		SELECT id, created_at FROM inserted
+	`
+	return r.db.QueryRowContext(ctx, query, order.UserID, order.RouteID, order.Status, order.TotalAmount, order.ExpiresAt, createdBy).Scan(&order.ID, &order.CreatedAt)
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id int64) (*models.Order, error) {
+	order := &models.Order{}
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	err := scanOrder(r.db.QueryRowContext(ctx, query, id), order)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	return order, err
 }
 
-func (r *orderRepository) GetByUserID(userID int64) ([]models.Order, error) {
-	query := `SELECT id, user_id, route_id, created_at, status, total_amount FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
-	rows, err := r.db.Query(query, userID)
+func (r *orderRepository) GetByUserID(ctx context.Context, userID int64) ([]models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+	return r.list(ctx, query, userID)
+}
+
+func (r *orderRepository) GetAll(ctx context.Context) ([]models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders ORDER BY created_at DESC`
+	return r.list(ctx, query)
+}
+
+func (r *orderRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.Order, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,56 +77,114 @@ func (r *orderRepository) GetByUserID(userID int64) ([]models.Order, error) {
 	var orders []models.Order
 	for rows.Next() {
 		var order models.Order
-		var routeID sql.NullInt64
-		if err := rows.Scan(&order.ID, &order.UserID, &routeID, &order.CreatedAt, &order.Status, &order.TotalAmount); err != nil {
+		if err := scanOrder(rows, &order); err != nil {
 			return nil, err
 		}
-		if routeID.Valid {
-			order.RouteID = &routeID.Int64
-		}
 		orders = append(orders, order)
 	}
 	return orders, rows.Err()
 }
 
-func (r *orderRepository) GetAll() ([]models.Order, error) {
-	query := `SELECT id, user_id, route_id, created_at, status, total_amount FROM orders ORDER BY created_at DESC`
-	rows, err := r.db.Query(query)
+// Update saves the order's amount and hold expiry. The status can only be
+// changed through Transition.
+func (r *orderRepository) Update(ctx context.Context, order *models.Order) error {
+	query := `UPDATE orders SET total_amount = $1, expires_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, order.TotalAmount, order.ExpiresAt, order.ID)
+	return err
+}
+
+// Transition moves the order to a new status if the state machine allows it
+// and records the change in the status history. changedBy is nil for changes
+// made by the system. If another request changed the status first, the
+// move is re-checked against the status now stored.
+func (r *orderRepository) Transition(ctx context.Context, order *models.Order, to models.OrderStatus, changedBy *int64) error {
+	if !order.Status.CanTransitionTo(to) {
+		return &models.TransitionError{From: order.Status, To: to}
+	}
+
+	query := `
+		WITH updated AS (
+			UPDATE orders SET status = $3::varchar
+			WHERE id = $1 AND status = $2::varchar
+			RETURNING id
+		), history AS (
+			INSERT INTO order_status_history (order_id, from_status, to_status, changed_by)
+			SELECT id, $2::varchar, $3::varchar, $4::bigint FROM updated
+		)
+		SELECT COUNT(*) FROM updated
+	`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, order.ID, order.Status, to, changedBy).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		var current models.OrderStatus
+		if err := r.db.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1`, order.ID).Scan(&current); err != nil {
+			return err
+		}
+		return &models.TransitionError{From: current, To: to}
+	}
+
+	order.Status = to
+	return nil
+}
+
+func (r *orderRepository) GetStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusChange, error) {
+	query := `SELECT id, order_id, from_status, to_status, changed_by, changed_at
+	          FROM order_status_history WHERE order_id = $1 ORDER BY changed_at, id`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []models.Order
+	var changes []models.OrderStatusChange
 	for rows.Next() {
-		var order models.Order
-		var routeID sql.NullInt64
-		if err := rows.Scan(&order.ID, &order.UserID, &routeID, &order.CreatedAt, &order.Status, &order.TotalAmount); err != nil {
+		var change models.OrderStatusChange
+		var fromStatus sql.NullString
+		var changedBy sql.NullInt64
+		if err := rows.Scan(&change.ID, &change.OrderID, &fromStatus, &change.ToStatus, &changedBy, &change.ChangedAt); err != nil {
 			return nil, err
 		}
-		if routeID.Valid {
-			order.RouteID = &routeID.Int64
+		if fromStatus.Valid {
+			from := models.OrderStatus(fromStatus.String)
+			change.FromStatus = &from
 		}
-		orders = append(orders, order)
+		if changedBy.Valid {
+			change.ChangedBy = &changedBy.Int64
+		}
+		changes = append(changes, change)
 	}
-	return orders, rows.Err()
+	return changes, rows.Err()
 }
 
-func (r *orderRepository) Update(order *models.Order) error {
-	query := `UPDATE orders SET status = $1, total_amount = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, order.Status, order.TotalAmount, order.ID)
-	return err
-}
-
-func (r *orderRepository) Delete(id int64) error {
+func (r *orderRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM orders WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *orderRepository) DeleteExpiredPending(maxAgeMinutes int) error {
-	query := `DELETE FROM orders WHERE status = 'PENDING' AND created_at < NOW() - INTERVAL '1 minute' * $1`
-	_, err := r.db.Exec(query, maxAgeMinutes)
-	return err
+// ExpirePending moves unpaid orders whose hold has run out to EXPIRED,
+// releases the seats held by their tickets and records the change as made
+// by the system
+func (r *orderRepository) ExpirePending(ctx context.Context) (int64, error) {
+	query := `
+		WITH expired AS (
+			UPDATE orders SET status = 'EXPIRED'
+			WHERE status = 'PENDING' AND expires_at < NOW()
+			RETURNING id
+		), released AS (
+			UPDATE tickets SET status = 'EXPIRED'
+			WHERE status = 'HELD' AND order_id IN (SELECT id FROM expired)
+			RETURNING id
+		), history AS (
+			INSERT INTO order_status_history (order_id, from_status, to_status)
+			SELECT id, 'PENDING', 'EXPIRED' FROM expired
+		)
+		SELECT COUNT(*) FROM expired
+	`
+	var count int64
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
 }
 
@@ -1,46 +1,48 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type passengerRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewPassengerRepository(db *sql.DB) PassengerRepository {
 	return &passengerRepository{db: db}
 }
 
-func (r *passengerRepository) Create(passenger *models.Passenger) error {
-	query := `INSERT INTO passengers (user_id, first_name, last_name, passport_data) VALUES ($1, $2, $3, $4) RETURNING id`
-	return r.db.QueryRow(query, passenger.UserID, passenger.FirstName, passenger.LastName, passenger.PassportData).Scan(&passenger.ID)
+func (r *passengerRepository) Create(ctx context.Context, passenger *models.Passenger) error {
+	query := `INSERT INTO passengers (user_id, first_name, last_name, passport_data, is_primary) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, passenger.UserID, passenger.FirstName, passenger.LastName, passenger.PassportData, passenger.IsPrimary).Scan(&passenger.ID)
 }
 
-func (r *passengerRepository) GetByID(id int64) (*models.Passenger, error) {
+func (r *passengerRepository) GetByID(ctx context.Context, id int64) (*models.Passenger, error) {
 	passenger := &models.Passenger{}
-	query := `SELECT id, user_id, first_name, last_name, passport_data FROM passengers WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&passenger.ID, &passenger.UserID, &passenger.FirstName, &passenger.LastName, &passenger.PassportData)
+	query := `SELECT id, user_id, first_name, last_name, passport_data, is_primary FROM passengers WHERE id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&passenger.ID, &passenger.UserID, &passenger.FirstName, &passenger.LastName, &passenger.PassportData, &passenger.IsPrimary)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return passenger, err
 }
 
-func (r *passengerRepository) GetByUserID(userID int64) (*models.Passenger, error) {
+// GetByUserID returns the user's own passenger profile
+func (r *passengerRepository) GetByUserID(ctx context.Context, userID int64) (*models.Passenger, error) {
 	passenger := &models.Passenger{}
-	query := `SELECT id, user_id, first_name, last_name, passport_data FROM passengers WHERE user_id = $1`
-	err := r.db.QueryRow(query, userID).Scan(&passenger.ID, &passenger.UserID, &passenger.FirstName, &passenger.LastName, &passenger.PassportData)
+	query := `SELECT id, user_id, first_name, last_name, passport_data, is_primary FROM passengers
+	          WHERE user_id = $1 ORDER BY is_primary DESC, id LIMIT 1`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&passenger.ID, &passenger.UserID, &passenger.FirstName, &passenger.LastName, &passenger.PassportData, &passenger.IsPrimary)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return passenger, err
 }
 
-func (r *passengerRepository) Update(passenger *models.Passenger) error {
+func (r *passengerRepository) Update(ctx context.Context, passenger *models.Passenger) error {
 	query := `UPDATE passengers SET first_name = $1, last_name = $2, passport_data = $3 WHERE id = $4`
-	_, err := r.db.Exec(query, passenger.FirstName, passenger.LastName, passenger.PassportData, passenger.ID)
+	_, err := r.db.ExecContext(ctx, query, passenger.FirstName, passenger.LastName, passenger.PassportData, passenger.ID)
 	return err
 }
-
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+type refreshTokenRepository struct {
+	db queryer
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (session_id, token_hash, access_jti, access_expires_at, expires_at)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, token.SessionID, token.TokenHash, token.AccessJTI, token.AccessExpiresAt, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	query := `SELECT id, session_id, token_hash, access_jti, access_expires_at, created_at, expires_at, used_at
+	          FROM refresh_tokens WHERE token_hash = $1`
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(&token.ID, &token.SessionID, &token.TokenHash, &token.AccessJTI,
+		&token.AccessExpiresAt, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// MarkUsed records that the token was exchanged for its successor
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, id)
+	return err
+}
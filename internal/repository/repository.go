@@ -1,102 +1,205 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"time"
+
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type Repositories struct {
-	User      UserRepository
-	Passenger PassengerRepository
-	Train     TrainRepository
-	Carriage  CarriageRepository
-	Seat      SeatRepository
-	Station   StationRepository
-	Route     RouteRepository
-	Order     OrderRepository
-	Ticket    TicketRepository
+	User         UserRepository
+	Passenger    PassengerRepository
+	Train        TrainRepository
+	Carriage     CarriageRepository
+	CarriageType CarriageTypeRepository
+	Seat         SeatRepository
+	Station      StationRepository
+	Route        RouteRepository
+	Order        OrderRepository
+	Ticket       TicketRepository
+	Calendar     CalendarRepository
+	RefreshToken RefreshTokenRepository
+	Session      SessionRepository
+	AccountToken AccountTokenRepository
+	Role         RoleRepository
+
+	// db is nil for repositories bound to a transaction
+	db *sql.DB
 }
 
 func NewRepositories(db *sql.DB) *Repositories {
+	repos := newRepositories(db)
+	repos.db = db
+	return repos
+}
+
+func newRepositories(q queryer) *Repositories {
 	return &Repositories{
-		User:      NewUserRepository(db),
-		Passenger: NewPassengerRepository(db),
-		Train:     NewTrainRepository(db),
-		Carriage:  NewCarriageRepository(db),
-		Seat:      NewSeatRepository(db),
-		Station:   NewStationRepository(db),
-		Route:     NewRouteRepository(db),
-		Order:     NewOrderRepository(db),
-		Ticket:    NewTicketRepository(db),
+		User:         &userRepository{db: q},
+		Passenger:    &passengerRepository{db: q},
+		Train:        &trainRepository{db: q},
+		Carriage:     &carriageRepository{db: q},
+		CarriageType: &carriageTypeRepository{db: q},
+		Seat:         &seatRepository{db: q},
+		Station:      &stationRepository{db: q},
+		Route:        &routeRepository{db: q},
+		Order:        &orderRepository{db: q},
+		Ticket:       &ticketRepository{db: q},
+		Calendar:     &calendarRepository{db: q},
+		RefreshToken: &refreshTokenRepository{db: q},
+		Session:      &sessionRepository{db: q},
+		AccountToken: &accountTokenRepository{db: q},
+		Role:         &roleRepository{db: q},
 	}
 }
 
 type UserRepository interface {
-	Create(user *models.User) error
-	GetByID(id int64) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	Update(user *models.User) error
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int64) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	GetAll(ctx context.Context, role string) ([]models.User, error)
 }
 
 type PassengerRepository interface {
-	Create(passenger *models.Passenger) error
-	GetByID(id int64) (*models.Passenger, error)
-	GetByUserID(userID int64) (*models.Passenger, error)
-	Update(passenger *models.Passenger) error
+	Create(ctx context.Context, passenger *models.Passenger) error
+	GetByID(ctx context.Context, id int64) (*models.Passenger, error)
+	GetByUserID(ctx context.Context, userID int64) (*models.Passenger, error)
+	Update(ctx context.Context, passenger *models.Passenger) error
 }
 
 type TrainRepository interface {
-	Create(train *models.Train) error
-	GetByID(id int64) (*models.Train, error)
-	GetAll() ([]models.Train, error)
-	Update(train *models.Train) error
-	Delete(id int64) error
+	Create(ctx context.Context, train *models.Train) error
+	GetByID(ctx context.Context, id int64) (*models.Train, error)
+	GetAll(ctx context.Context) ([]models.Train, error)
+	Update(ctx context.Context, train *models.Train) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type CarriageTypeRepository interface {
+	Create(ctx context.Context, t *models.CarriageType) error
+	GetByID(ctx context.Context, id int64) (*models.CarriageType, error)
+	GetByName(ctx context.Context, name string) (*models.CarriageType, error)
+	GetAll(ctx context.Context) ([]models.CarriageType, error)
+	Update(ctx context.Context, t *models.CarriageType) error
+	Delete(ctx context.Context, id int64) error
+	HasCarriages(ctx context.Context, id int64) (bool, error)
 }
 
 type CarriageRepository interface {
-	Create(carriage *models.Carriage) error
-	GetByID(id int64) (*models.Carriage, error)
-	GetByTrainID(trainID int64) ([]models.Carriage, error)
+	Create(ctx context.Context, carriage *models.Carriage) error
+	GetByID(ctx context.Context, id int64) (*models.Carriage, error)
+	GetByTrainID(ctx context.Context, trainID int64) ([]models.Carriage, error)
+	Update(ctx context.Context, carriage *models.Carriage) error
+	Delete(ctx context.Context, id int64) error
+	HasSoldTickets(ctx context.Context, id int64) (bool, error)
 }
 
 type SeatRepository interface {
-	Create(seat *models.Seat) error
-	GetByID(id int64) (*models.Seat, error)
-	GetByCarriageID(carriageID int64) ([]models.Seat, error)
-	IsAvailable(seatID int64, date string) (bool, error)
+	Create(ctx context.Context, seat *models.Seat) error
+	GetByID(ctx context.Context, id int64) (*models.Seat, error)
+	GetByCarriageID(ctx context.Context, carriageID int64) ([]models.Seat, error)
+	DeleteByCarriageID(ctx context.Context, carriageID int64) error
+	IsAvailable(ctx context.Context, routeID, seatID int64, date string, segment models.Segment) (bool, error)
+	GetOccupied(ctx context.Context, routeID int64, date string, segment models.Segment) (map[int64]models.TicketStatus, error)
+	CountAvailable(ctx context.Context, routeID int64, date string, segment models.Segment, filter models.SeatFilter) (int, error)
 }
 
 type StationRepository interface {
-	Create(station *models.Station) error
-	GetByID(id int64) (*models.Station, error)
-	GetByCity(city string) ([]models.Station, error)
-	GetAll() ([]models.Station, error)
+	Create(ctx context.Context, station *models.Station) error
+	GetByID(ctx context.Context, id int64) (*models.Station, error)
+	GetByCity(ctx context.Context, city string) ([]models.Station, error)
+	GetAll(ctx context.Context) ([]models.Station, error)
+	Update(ctx context.Context, station *models.Station) error
+	Delete(ctx context.Context, id int64) error
 }
 
 type RouteRepository interface {
-	Create(route *models.Route) error
-	GetByID(id int64) (*models.Route, error)
-	Search(fromCity, toCity, date string) ([]models.Route, error)
-	Update(route *models.Route) error
-	Delete(id int64) error
-	AddStation(routeID, stationID int64, arrivalTime, departureTime string, stopOrder int) error
-	GetStations(routeID int64) ([]models.RouteStation, error)
+	Create(ctx context.Context, route *models.Route) error
+	GetByID(ctx context.Context, id int64) (*models.Route, error)
+	GetAll(ctx context.Context) ([]models.Route, error)
+	Search(ctx context.Context, fromCity, toCity, date string) ([]models.Route, error)
+	Update(ctx context.Context, route *models.Route) error
+	Delete(ctx context.Context, id int64) error
+	ReplaceStations(ctx context.Context, routeID int64, stops []models.RouteStation) error
+	GetStations(ctx context.Context, routeID int64) ([]models.RouteStation, error)
+	GetAllStations(ctx context.Context) (map[int64][]models.RouteStation, error)
 }
 
 type OrderRepository interface {
-	Create(order *models.Order) error
-	GetByID(id int64) (*models.Order, error)
-	GetByUserID(userID int64) ([]models.Order, error)
-	GetAll() ([]models.Order, error)
-	Update(order *models.Order) error
-	Delete(id int64) error
-	DeleteExpiredPending(maxAgeMinutes int) error
+	Create(ctx context.Context, order *models.Order, createdBy int64) error
+	GetByID(ctx context.Context, id int64) (*models.Order, error)
+	GetByUserID(ctx context.Context, userID int64) ([]models.Order, error)
+	GetAll(ctx context.Context) ([]models.Order, error)
+	Update(ctx context.Context, order *models.Order) error
+	Delete(ctx context.Context, id int64) error
+	ExpirePending(ctx context.Context) (int64, error)
+	Transition(ctx context.Context, order *models.Order, to models.OrderStatus, changedBy *int64) error
+	GetStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusChange, error)
 }
 
 type TicketRepository interface {
-	Create(ticket *models.Ticket) error
-	GetByID(id int64) (*models.Ticket, error)
-	GetByOrderID(orderID int64) ([]models.Ticket, error)
-	Update(ticket *models.Ticket) error
+	Create(ctx context.Context, ticket *models.Ticket) error
+	GetByID(ctx context.Context, id int64) (*models.Ticket, error)
+	GetByNumber(ctx context.Context, number string) (*models.Ticket, error)
+	GetByOrderID(ctx context.Context, orderID int64) ([]models.Ticket, error)
+	Update(ctx context.Context, ticket *models.Ticket) error
+	UpdateStatusByOrderID(ctx context.Context, orderID int64, from, to models.TicketStatus) (int64, error)
+	HasUpcomingByRouteID(ctx context.Context, routeID int64) (bool, error)
 }
 
+type CalendarRepository interface {
+	Create(ctx context.Context, cal *models.ServiceCalendar) error
+	GetByID(ctx context.Context, id int64) (*models.ServiceCalendar, error)
+	GetAll(ctx context.Context) ([]models.ServiceCalendar, error)
+	Update(ctx context.Context, cal *models.ServiceCalendar) error
+	Delete(ctx context.Context, id int64) error
+	SetException(ctx context.Context, e *models.CalendarException) error
+	DeleteException(ctx context.Context, calendarID int64, date time.Time) (bool, error)
+	GetHolidays(ctx context.Context) ([]models.Holiday, error)
+	IsHoliday(ctx context.Context, date time.Time) (bool, error)
+	SetHoliday(ctx context.Context, h *models.Holiday) error
+	DeleteHoliday(ctx context.Context, date time.Time) (bool, error)
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	MarkUsed(ctx context.Context, id int64) error
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByID(ctx context.Context, id int64) (*models.Session, error)
+	GetActiveByUserID(ctx context.Context, userID int64) ([]models.Session, error)
+	Touch(ctx context.Context, id int64, ipAddress string, expiresAt time.Time) error
+	RevokeAccessTokensByUserID(ctx context.Context, userID int64) ([]models.RevokedToken, error)
+	Revoke(ctx context.Context, id int64) ([]models.RevokedToken, error)
+	RevokeAllByUserID(ctx context.Context, userID int64) ([]models.RevokedToken, error)
+	GetRevokedTokens(ctx context.Context) ([]models.RevokedToken, error)
+	PurgeRevokedTokens(ctx context.Context) (int64, error)
+}
+
+type AccountTokenRepository interface {
+	Create(ctx context.Context, token *models.AccountToken) error
+	GetByHash(ctx context.Context, purpose models.TokenPurpose, hash string) (*models.AccountToken, error)
+	MarkUsed(ctx context.Context, id int64) (bool, error)
+	LastCreatedAt(ctx context.Context, userID int64, purpose models.TokenPurpose) (time.Time, error)
+	InvalidateByUserID(ctx context.Context, userID int64, purpose models.TokenPurpose) error
+}
+
+type RoleRepository interface {
+	GetAll(ctx context.Context) ([]models.Role, error)
+	GetByNames(ctx context.Context, names []string) ([]models.Role, error)
+	GetPermissionsByUserID(ctx context.Context, userID int64) ([]string, error)
+	SetUserRoles(ctx context.Context, userID int64, roleIDs []int64, assignedBy int64) error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+type roleRepository struct {
+	db queryer
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+const roleQuery = `SELECT r.id, r.name, r.description,
+	ARRAY(SELECT rp.permission FROM role_permissions rp WHERE rp.role_id = r.id ORDER BY rp.permission)
+	FROM roles r`
+
+func (r *roleRepository) GetAll(ctx context.Context) ([]models.Role, error) {
+	return r.roles(ctx, roleQuery+` ORDER BY r.name`)
+}
+
+// GetByNames returns the roles with the names; names without a role are
+// left out
+func (r *roleRepository) GetByNames(ctx context.Context, names []string) ([]models.Role, error) {
+	return r.roles(ctx, roleQuery+` WHERE r.name = ANY($1) ORDER BY r.name`, pq.Array(names))
+}
+
+// GetPermissionsByUserID returns every permission the user's roles grant
+func (r *roleRepository) GetPermissionsByUserID(ctx context.Context, userID int64) ([]string, error) {
+	query := `SELECT DISTINCT rp.permission FROM user_roles ur
+	          JOIN role_permissions rp ON rp.role_id = ur.role_id
+	          WHERE ur.user_id = $1 ORDER BY rp.permission`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// SetUserRoles makes roleIDs the user's roles, keeping when the ones the user
+// already had were assigned
+func (r *roleRepository) SetUserRoles(ctx context.Context, userID int64, roleIDs []int64, assignedBy int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND NOT role_id = ANY($2)`,
+		userID, pq.Array(roleIDs))
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO user_roles (user_id, role_id, assigned_by)
+	          SELECT $1, UNNEST($2::INTEGER[]), $3
+	          ON CONFLICT (user_id, role_id) DO NOTHING`
+	_, err = r.db.ExecContext(ctx, query, userID, pq.Array(roleIDs), assignedBy)
+	return err
+}
+
+func (r *roleRepository) roles(ctx context.Context, query string, args ...interface{}) ([]models.Role, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, pq.Array(&role.Permissions)); err != nil {
+			return nil, err
+		}
+		if role.Permissions == nil {
+			role.Permissions = []string{}
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
@@ -1,45 +1,45 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type routeRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewRouteRepository(db *sql.DB) RouteRepository {
 	return &routeRepository{db: db}
 }
 
-func (r *routeRepository) Create(route *models.Route) error {
-	query := `INSERT INTO routes (name, train_id, price) VALUES ($1, $2, $3) RETURNING id`
-	return r.db.QueryRow(query, route.Name, route.TrainID, route.Price).Scan(&route.ID)
+func scanRoute(row interface{ Scan(...interface{}) error }, route *models.Route) error {
+	return row.Scan(&route.ID, &route.Name, &route.TrainID, &route.Price, &route.CalendarID)
 }
 
-func (r *routeRepository) GetByID(id int64) (*models.Route, error) {
+func (r *routeRepository) Create(ctx context.Context, route *models.Route) error {
+	query := `INSERT INTO routes (name, train_id, price, calendar_id) VALUES ($1, $2, $3, $4) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, route.Name, route.TrainID, route.Price, route.CalendarID).Scan(&route.ID)
+}
+
+func (r *routeRepository) GetByID(ctx context.Context, id int64) (*models.Route, error) {
 	route := &models.Route{}
-	query := `SELECT id, name, train_id, price FROM routes WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&route.ID, &route.Name, &route.TrainID, &route.Price)
+	query := `SELECT id, name, train_id, price, calendar_id FROM routes WHERE id = $1`
+	err := scanRoute(r.db.QueryRowContext(ctx, query, id), route)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return route, err
 }
 
-func (r *routeRepository) Search(fromCity, toCity, date string) ([]models.Route, error) {
-	query := `
-		SELECT DISTINCT r.id, r.name, r.train_id, r.price
-		FROM routes r
-		INNER JOIN route_stations rs1 ON r.id = rs1.route_id
-		INNER JOIN stations s1 ON rs1.station_id = s1.id
-		INNER JOIN route_stations rs2 ON r.id = rs2.route_id
-		INNER JOIN stations s2 ON rs2.station_id = s2.id
-		WHERE s1.city = $1 AND s2.city = $2 AND rs1.stop_order < rs2.stop_order
-	`
-	rows, err := r.db.Query(query, fromCity, toCity)
+func (r *routeRepository) GetAll(ctx context.Context) ([]models.Route, error) {
+	return r.list(ctx, `SELECT id, name, train_id, price, calendar_id FROM routes ORDER BY id`)
+}
+
+func (r *routeRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.Route, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +48,7 @@ func (r *routeRepository) Search(fromCity, toCity, date string) ([]models.Route,
 	var routes []models.Route
 	for rows.Next() {
 		var route models.Route
-		if err := rows.Scan(&route.ID, &route.Name, &route.TrainID, &route.Price); err != nil {
+		if err := scanRoute(rows, &route); err != nil {
 			return nil, err
 		}
 		routes = append(routes, route)
@@ -56,47 +56,93 @@ func (r *routeRepository) Search(fromCity, toCity, date string) ([]models.Route,
 	return routes, rows.Err()
 }
 
-func (r *routeRepository) Update(route *models.Route) error {
-	query := `UPDATE routes SET name = $1, train_id = $2, price = $3 WHERE id = $4`
-	_, err := r.db.Exec(query, route.Name, route.TrainID, route.Price, route.ID)
+func (r *routeRepository) Search(ctx context.Context, fromCity, toCity, date string) ([]models.Route, error) {
+	query := `
+		SELECT DISTINCT r.id, r.name, r.train_id, r.price, r.calendar_id
+		FROM routes r
+		INNER JOIN route_stations rs1 ON r.id = rs1.route_id
+		INNER JOIN stations s1 ON rs1.station_id = s1.id
+		INNER JOIN route_stations rs2 ON r.id = rs2.route_id
+		INNER JOIN stations s2 ON rs2.station_id = s2.id
+		WHERE s1.city = $1 AND s2.city = $2 AND rs1.stop_order < rs2.stop_order
+		  AND (($3::date + rs1.departure_day_offset + rs1.departure_time) AT TIME ZONE s1.time_zone) > NOW()
+	`
+	return r.list(ctx, query, fromCity, toCity, date)
+}
+
+func (r *routeRepository) Update(ctx context.Context, route *models.Route) error {
+	query := `UPDATE routes SET name = $1, train_id = $2, price = $3, calendar_id = $4 WHERE id = $5`
+	_, err := r.db.ExecContext(ctx, query, route.Name, route.TrainID, route.Price, route.CalendarID, route.ID)
 	return err
 }
 
-func (r *routeRepository) Delete(id int64) error {
+func (r *routeRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM routes WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *routeRepository) AddStation(routeID, stationID int64, arrivalTime, departureTime string, stopOrder int) error {
-	var arrTime, depTime interface{}
-	if arrivalTime != "" {
-		t, _ := time.Parse("15:04:05", arrivalTime)
-		arrTime = t
+// ReplaceStations replaces every stop of the route. It should run in a
+// transaction so that the route is never seen without its stops.
+func (r *routeRepository) ReplaceStations(ctx context.Context, routeID int64, stops []models.RouteStation) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM route_stations WHERE route_id = $1`, routeID); err != nil {
+		return err
 	}
-	if departureTime != "" {
-		t, _ := time.Parse("15:04:05", departureTime)
-		depTime = t
+	query := `INSERT INTO route_stations (route_id, station_id, arrival_time, departure_time, arrival_day_offset, departure_day_offset, stop_order)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, stop := range stops {
+		_, err := r.db.ExecContext(ctx, query, routeID, stop.StationID, clockValue(stop.ArrivalTime), clockValue(stop.DepartureTime),
+			stop.ArrivalDayOffset, stop.DepartureDayOffset, stop.StopOrder)
+		if err != nil {
+			return err
+		}
 	}
-	
-	query := `INSERT INTO route_stations (route_id, station_id, arrival_time, departure_time, stop_order) 
-	          VALUES ($1, $2, $3, $4, $5) 
-	          ON CONFLICT (route_id, station_id) DO UPDATE SET 
-	          arrival_time = EXCLUDED.arrival_time, 
-	          departure_time = EXCLUDED.departure_time, 
-	          stop_order = EXCLUDED.stop_order`
-	_, err := r.db.Exec(query, routeID, stationID, arrTime, depTime, stopOrder)
-	return err
+	return nil
+}
+
+// clockValue formats a time of day for a TIME column
+func clockValue(clock *time.Time) interface{} {
+	if clock == nil {
+		return nil
+	}
+	return clock.Format("15:04:05")
+}
+
+func (r *routeRepository) GetStations(ctx context.Context, routeID int64) ([]models.RouteStation, error) {
+	query := `
+		SELECT rs.route_id, rs.station_id, rs.arrival_time, rs.departure_time,
+		       rs.arrival_day_offset, rs.departure_day_offset, rs.stop_order, s.time_zone, s.city
+		FROM route_stations rs
+		INNER JOIN stations s ON rs.station_id = s.id
+		WHERE rs.route_id = $1
+		ORDER BY rs.stop_order
+	`
+	return r.listStations(ctx, query, routeID)
 }
 
-func (r *routeRepository) GetStations(routeID int64) ([]models.RouteStation, error) {
+// GetAllStations returns the stops of every route, keyed by route ID and in
+// stop order
+func (r *routeRepository) GetAllStations(ctx context.Context) (map[int64][]models.RouteStation, error) {
 	query := `
-		SELECT route_id, station_id, arrival_time, departure_time, stop_order
-		FROM route_stations
-		WHERE route_id = $1
-		ORDER BY stop_order
+		SELECT rs.route_id, rs.station_id, rs.arrival_time, rs.departure_time,
+		       rs.arrival_day_offset, rs.departure_day_offset, rs.stop_order, s.time_zone, s.city
+		FROM route_stations rs
+		INNER JOIN stations s ON rs.station_id = s.id
+		ORDER BY rs.route_id, rs.stop_order
 	`
-	rows, err := r.db.Query(query, routeID)
+	stops, err := r.listStations(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	byRoute := make(map[int64][]models.RouteStation)
+	for _, stop := range stops {
+		byRoute[stop.RouteID] = append(byRoute[stop.RouteID], stop)
+	}
+	return byRoute, nil
+}
+
+func (r *routeRepository) listStations(ctx context.Context, query string, args ...interface{}) ([]models.RouteStation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +152,7 @@ func (r *routeRepository) GetStations(routeID int64) ([]models.RouteStation, err
 	for rows.Next() {
 		var rs models.RouteStation
 		var arrTime, depTime sql.NullTime
-		if err := rows.Scan(&rs.RouteID, &rs.StationID, &arrTime, &depTime, &rs.StopOrder); err != nil {
+		if err := rows.Scan(&rs.RouteID, &rs.StationID, &arrTime, &depTime, &rs.ArrivalDayOffset, &rs.DepartureDayOffset, &rs.StopOrder, &rs.TimeZone, &rs.City); err != nil {
 			return nil, err
 		}
 		if arrTime.Valid {
@@ -1,36 +1,67 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type seatRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewSeatRepository(db *sql.DB) SeatRepository {
 	return &seatRepository{db: db}
 }
 
-func (r *seatRepository) Create(seat *models.Seat) error {
-	query := `INSERT INTO seats (carriage_id, number) VALUES ($1, $2) RETURNING id`
-	return r.db.QueryRow(query, seat.CarriageID, seat.Number).Scan(&seat.ID)
+const seatColumns = `id, carriage_id, number, position, compartment, placement, direction, wheelchair_space, reduced_mobility`
+
+func scanSeat(row interface{ Scan(...interface{}) error }, seat *models.Seat) error {
+	return row.Scan(&seat.ID, &seat.CarriageID, &seat.Number, &seat.Position, &seat.Compartment,
+		&seat.Placement, &seat.Direction, &seat.WheelchairSpace, &seat.ReducedMobility)
+}
+
+// seatFilterClause restricts seats aliased as s to those matching a
+// SeatFilter. It is formatted with the numbers of the five placeholders that
+// take the values returned by seatFilterArgs.
+const seatFilterClause = `
+		  AND (cardinality($%[1]d::varchar[]) = 0 OR s.position = ANY($%[1]d::varchar[]))
+		  AND ($%[2]d::varchar = '' OR s.placement = $%[2]d::varchar)
+		  AND ($%[3]d::varchar = '' OR s.direction = $%[3]d::varchar)
+		  AND (NOT $%[4]d::boolean OR s.wheelchair_space)
+		  AND (NOT $%[5]d::boolean OR s.reduced_mobility)`
+
+func seatFilterArgs(filter models.SeatFilter) []interface{} {
+	positions := make([]string, len(filter.Positions))
+	for i, p := range filter.Positions {
+		positions[i] = string(p)
+	}
+	return []interface{}{pq.Array(positions), string(filter.Placement), string(filter.Direction), filter.WheelchairSpace, filter.ReducedMobility}
+}
+
+func (r *seatRepository) Create(ctx context.Context, seat *models.Seat) error {
+	query := `INSERT INTO seats (carriage_id, number, position, compartment, placement, direction, wheelchair_space, reduced_mobility)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, seat.CarriageID, seat.Number, seat.Position, seat.Compartment,
+		seat.Placement, seat.Direction, seat.WheelchairSpace, seat.ReducedMobility).Scan(&seat.ID)
 }
 
-func (r *seatRepository) GetByID(id int64) (*models.Seat, error) {
+func (r *seatRepository) GetByID(ctx context.Context, id int64) (*models.Seat, error) {
 	seat := &models.Seat{}
-	query := `SELECT id, carriage_id, number FROM seats WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&seat.ID, &seat.CarriageID, &seat.Number)
+	query := `SELECT ` + seatColumns + ` FROM seats WHERE id = $1`
+	err := scanSeat(r.db.QueryRowContext(ctx, query, id), seat)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return seat, err
 }
 
-func (r *seatRepository) GetByCarriageID(carriageID int64) ([]models.Seat, error) {
-	query := `SELECT id, carriage_id, number FROM seats WHERE carriage_id = $1 ORDER BY number`
-	rows, err := r.db.Query(query, carriageID)
+func (r *seatRepository) GetByCarriageID(ctx context.Context, carriageID int64) ([]models.Seat, error) {
+	query := `SELECT ` + seatColumns + ` FROM seats WHERE carriage_id = $1 ORDER BY number`
+	rows, err := r.db.QueryContext(ctx, query, carriageID)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +70,7 @@ func (r *seatRepository) GetByCarriageID(carriageID int64) ([]models.Seat, error
 	var seats []models.Seat
 	for rows.Next() {
 		var seat models.Seat
-		if err := rows.Scan(&seat.ID, &seat.CarriageID, &seat.Number); err != nil {
+		if err := scanSeat(rows, &seat); err != nil {
 			return nil, err
 		}
 		seats = append(seats, seat)
@@ -47,16 +78,80 @@ func (r *seatRepository) GetByCarriageID(carriageID int64) ([]models.Seat, error
 	return seats, rows.Err()
 }
 
-func (r *seatRepository) IsAvailable(seatID int64, date string) (bool, error) {
+// occupiesSegment matches tickets aliased as t that hold their seat on any
+// leg of the segment bounded by the stop orders in the two placeholders.
+// Stop ranges are half-open, so a ticket ending at a stop doesn't clash with
+// one starting there.
+const occupiesSegment = `t.status IN ('HELD', 'ACTIVE')
+		AND int4range(t.from_stop_order, t.to_stop_order) && int4range($%d::int, $%d::int)`
+
+// IsAvailable reports whether the seat is free on every leg of the segment
+func (r *seatRepository) IsAvailable(ctx context.Context, routeID, seatID int64, date string, segment models.Segment) (bool, error) {
 	query := `
-		SELECT COUNT(*) FROM tickets 
-		WHERE seat_id = $1 AND departure_date = $2 AND status = 'ACTIVE'
-	`
+		SELECT COUNT(*) FROM tickets t
+		WHERE t.route_id = $1 AND t.seat_id = $2 AND t.departure_date = $3
+		  AND ` + fmt.Sprintf(occupiesSegment, 4, 5)
 	var count int
-	err := r.db.QueryRow(query, seatID, date).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, routeID, seatID, date, segment.FromStop, segment.ToStop).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count == 0, nil
 }
 
+// GetOccupied returns, keyed by seat ID, the seats of the route taken on any
+// leg of the segment on date. A seat sold on one leg and held on another is
+// reported as sold.
+func (r *seatRepository) GetOccupied(ctx context.Context, routeID int64, date string, segment models.Segment) (map[int64]models.TicketStatus, error) {
+	query := `
+		SELECT t.seat_id, t.status FROM tickets t
+		WHERE t.route_id = $1 AND t.departure_date = $2 AND t.seat_id IS NOT NULL
+		  AND ` + fmt.Sprintf(occupiesSegment, 3, 4)
+	rows, err := r.db.QueryContext(ctx, query, routeID, date, segment.FromStop, segment.ToStop)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	occupied := make(map[int64]models.TicketStatus)
+	for rows.Next() {
+		var seatID int64
+		var status models.TicketStatus
+		if err := rows.Scan(&seatID, &status); err != nil {
+			return nil, err
+		}
+		if occupied[seatID] != models.TicketActive {
+			occupied[seatID] = status
+		}
+	}
+	return occupied, rows.Err()
+}
+
+// CountAvailable counts the seats of the route's train that are free on
+// every leg of the segment on date and match filter
+func (r *seatRepository) CountAvailable(ctx context.Context, routeID int64, date string, segment models.Segment, filter models.SeatFilter) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM seats s
+		INNER JOIN carriages c ON s.carriage_id = c.id
+		INNER JOIN routes r ON r.train_id = c.train_id
+		WHERE r.id = $1
+		  AND NOT EXISTS (
+		      SELECT 1 FROM tickets t
+		      WHERE t.route_id = r.id AND t.seat_id = s.id AND t.departure_date = $2
+		        AND ` + fmt.Sprintf(occupiesSegment, 3, 4) + `
+		  )` + fmt.Sprintf(seatFilterClause, 5, 6, 7, 8, 9)
+	args := append([]interface{}{routeID, date, segment.FromStop, segment.ToStop}, seatFilterArgs(filter)...)
+	var count int
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// DeleteByCarriageID removes every seat of the carriage
+func (r *seatRepository) DeleteByCarriageID(ctx context.Context, carriageID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM seats WHERE carriage_id = $1`, carriageID)
+	if isConstraintViolation(err, foreignKeyViolation, "tickets_seat_id_fkey") {
+		return ErrCarriageInUse
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+type sessionRepository struct {
+	db queryer
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, device, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }, session *models.Session) error {
+	return row.Scan(&session.ID, &session.UserID, &session.Device, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt)
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	query := `INSERT INTO sessions (user_id, device, user_agent, ip_address, expires_at)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, last_seen_at`
+	return r.db.QueryRowContext(ctx, query, session.UserID, session.Device, session.UserAgent, session.IPAddress, session.ExpiresAt).
+		Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id int64) (*models.Session, error) {
+	session := &models.Session{}
+	err := scanSession(r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id), session)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return session, err
+}
+
+// GetActiveByUserID returns the user's sessions that are neither revoked nor
+// expired, most recently used first
+func (r *sessionRepository) GetActiveByUserID(ctx context.Context, userID int64) ([]models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+	          WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	          ORDER BY last_seen_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Touch records a refresh of the session from the address and extends it
+// until expiresAt
+func (r *sessionRepository) Touch(ctx context.Context, id int64, ipAddress string, expiresAt time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = NOW(), ip_address = $1, expires_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, ipAddress, expiresAt, id)
+	return err
+}
+
+// Revoke revokes the session and returns the access tokens issued to it that
+// are still valid, now revoked as well
+func (r *sessionRepository) Revoke(ctx context.Context, id int64) ([]models.RevokedToken, error) {
+	return r.revoke(ctx, `id = $1`, id)
+}
+
+// RevokeAllByUserID revokes every session of the user
+func (r *sessionRepository) RevokeAllByUserID(ctx context.Context, userID int64) ([]models.RevokedToken, error) {
+	return r.revoke(ctx, `user_id = $1`, userID)
+}
+
+func (r *sessionRepository) revoke(ctx context.Context, where string, arg interface{}) ([]models.RevokedToken, error) {
+	query := `WITH revoked AS (
+	              UPDATE sessions SET revoked_at = NOW()
+	              WHERE ` + where + ` AND revoked_at IS NULL
+	              RETURNING id
+	          )
+	          INSERT INTO revoked_tokens (jti, expires_at)
+	          SELECT rt.access_jti, rt.access_expires_at FROM refresh_tokens rt
+	          WHERE rt.session_id IN (SELECT id FROM revoked) AND rt.access_expires_at > NOW()
+	          ON CONFLICT (jti) DO NOTHING
+	          RETURNING jti, expires_at`
+	return r.revokedTokens(ctx, query, arg)
+}
+
+// RevokeAccessTokensByUserID revokes the user's live access tokens but keeps
+// the sessions, so clients refresh and get tokens with the user's current
+// permissions
+func (r *sessionRepository) RevokeAccessTokensByUserID(ctx context.Context, userID int64) ([]models.RevokedToken, error) {
+	query := `INSERT INTO revoked_tokens (jti, expires_at)
+	          SELECT rt.access_jti, rt.access_expires_at FROM refresh_tokens rt
+	          JOIN sessions s ON s.id = rt.session_id
+	          WHERE s.user_id = $1 AND s.revoked_at IS NULL AND rt.access_expires_at > NOW()
+	          ON CONFLICT (jti) DO NOTHING
+	          RETURNING jti, expires_at`
+	return r.revokedTokens(ctx, query, userID)
+}
+
+// GetRevokedTokens returns the revoked access tokens that haven't expired
+func (r *sessionRepository) GetRevokedTokens(ctx context.Context) ([]models.RevokedToken, error) {
+	return r.revokedTokens(ctx, `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > NOW()`)
+}
+
+// PurgeRevokedTokens forgets revoked access tokens that have expired anyway
+func (r *sessionRepository) PurgeRevokedTokens(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *sessionRepository) revokedTokens(ctx context.Context, query string, args ...interface{}) ([]models.RevokedToken, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.RevokedToken
+	for rows.Next() {
+		var t models.RevokedToken
+		if err := rows.Scan(&t.JTI, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
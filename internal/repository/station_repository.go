@@ -1,36 +1,37 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type stationRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewStationRepository(db *sql.DB) StationRepository {
 	return &stationRepository{db: db}
 }
 
-func (r *stationRepository) Create(station *models.Station) error {
-	query := `INSERT INTO stations (name, city) VALUES ($1, $2) RETURNING id`
-	return r.db.QueryRow(query, station.Name, station.City).Scan(&station.ID)
+func (r *stationRepository) Create(ctx context.Context, station *models.Station) error {
+	query := `INSERT INTO stations (name, city, time_zone) VALUES ($1, $2, $3) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, station.Name, station.City, station.TimeZone).Scan(&station.ID)
 }
 
-func (r *stationRepository) GetByID(id int64) (*models.Station, error) {
+func (r *stationRepository) GetByID(ctx context.Context, id int64) (*models.Station, error) {
 	station := &models.Station{}
-	query := `SELECT id, name, city FROM stations WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&station.ID, &station.Name, &station.City)
+	query := `SELECT id, name, city, time_zone FROM stations WHERE id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&station.ID, &station.Name, &station.City, &station.TimeZone)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return station, err
 }
 
-func (r *stationRepository) GetByCity(city string) ([]models.Station, error) {
-	query := `SELECT id, name, city FROM stations WHERE city = $1 ORDER BY name`
-	rows, err := r.db.Query(query, city)
+func (r *stationRepository) GetByCity(ctx context.Context, city string) ([]models.Station, error) {
+	query := `SELECT id, name, city, time_zone FROM stations WHERE city = $1 ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query, city)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +40,7 @@ func (r *stationRepository) GetByCity(city string) ([]models.Station, error) {
 	var stations []models.Station
 	for rows.Next() {
 		var station models.Station
-		if err := rows.Scan(&station.ID, &station.Name, &station.City); err != nil {
+		if err := rows.Scan(&station.ID, &station.Name, &station.City, &station.TimeZone); err != nil {
 			return nil, err
 		}
 		stations = append(stations, station)
@@ -47,9 +48,9 @@ func (r *stationRepository) GetByCity(city string) ([]models.Station, error) {
 	return stations, rows.Err()
 }
 
-func (r *stationRepository) GetAll() ([]models.Station, error) {
-	query := `SELECT id, name, city FROM stations ORDER BY city, name`
-	rows, err := r.db.Query(query)
+func (r *stationRepository) GetAll(ctx context.Context) ([]models.Station, error) {
+	query := `SELECT id, name, city, time_zone FROM stations ORDER BY city, name`
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +59,7 @@ func (r *stationRepository) GetAll() ([]models.Station, error) {
 	var stations []models.Station
 	for rows.Next() {
 		var station models.Station
-		if err := rows.Scan(&station.ID, &station.Name, &station.City); err != nil {
+		if err := rows.Scan(&station.ID, &station.Name, &station.City, &station.TimeZone); err != nil {
 			return nil, err
 		}
 		stations = append(stations, station)
@@ -66,3 +67,27 @@ func (r *stationRepository) GetAll() ([]models.Station, error) {
 	return stations, rows.Err()
 }
 
+func (r *stationRepository) Update(ctx context.Context, station *models.Station) error {
+	query := `UPDATE stations SET name = $1, city = $2, time_zone = $3 WHERE id = $4`
+	_, err := r.db.ExecContext(ctx, query, station.Name, station.City, station.TimeZone, station.ID)
+	return err
+}
+
+// Delete removes a station that no route calls at and no ticket refers to
+func (r *stationRepository) Delete(ctx context.Context, id int64) error {
+	var inUse bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM route_stations WHERE station_id = $1)`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrStationInUse
+	}
+
+	_, err = r.db.ExecContext(ctx, `DELETE FROM stations WHERE id = $1`, id)
+	if isConstraintViolation(err, foreignKeyViolation, "tickets_from_station_id_fkey") ||
+		isConstraintViolation(err, foreignKeyViolation, "tickets_to_station_id_fkey") {
+		return ErrStationInUse
+	}
+	return err
+}
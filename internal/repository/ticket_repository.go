@@ -1,48 +1,66 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type ticketRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewTicketRepository(db *sql.DB) TicketRepository {
 	return &ticketRepository{db: db}
 }
 
-func (r *ticketRepository) Create(ticket *models.Ticket) error {
-	query := `INSERT INTO tickets (order_id, seat_id, passenger_id, departure_date, price, ticket_number, status) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
-	return r.db.QueryRow(query, ticket.OrderID, ticket.SeatID, ticket.PassengerID, ticket.DepartureDate, 
+const ticketColumns = `id, order_id, route_id, seat_id, passenger_id, departure_date,
+	from_station_id, to_station_id, from_stop_order, to_stop_order, departs_at, arrives_at, price, ticket_number, status`
+
+func scanTicket(row interface{ Scan(...interface{}) error }, ticket *models.Ticket) error {
+	return row.Scan(&ticket.ID, &ticket.OrderID, &ticket.RouteID, &ticket.SeatID, &ticket.PassengerID, &ticket.DepartureDate,
+		&ticket.FromStationID, &ticket.ToStationID, &ticket.FromStop, &ticket.ToStop, &ticket.DepartsAt, &ticket.ArrivesAt,
+		&ticket.Price, &ticket.TicketNumber, &ticket.Status)
+}
+
+func (r *ticketRepository) Create(ctx context.Context, ticket *models.Ticket) error {
+	query := `INSERT INTO tickets (order_id, route_id, seat_id, passenger_id, departure_date,
+	                               from_station_id, to_station_id, from_stop_order, to_stop_order, departs_at, arrives_at,
+	                               price, ticket_number, status)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, ticket.OrderID, ticket.RouteID, ticket.SeatID, ticket.PassengerID, ticket.DepartureDate,
+		ticket.FromStationID, ticket.ToStationID, ticket.FromStop, ticket.ToStop, ticket.DepartsAt, ticket.ArrivesAt,
 		ticket.Price, ticket.TicketNumber, ticket.Status).Scan(&ticket.ID)
+	if isConstraintViolation(err, exclusionViolation, "excl_tickets_seat_segment") {
+		return ErrSeatUnavailable
+	}
+	return err
 }
 
-func (r *ticketRepository) GetByID(id int64) (*models.Ticket, error) {
+func (r *ticketRepository) GetByID(ctx context.Context, id int64) (*models.Ticket, error) {
 	ticket := &models.Ticket{}
-	query := `SELECT id, order_id, seat_id, passenger_id, departure_date, price, ticket_number, status 
-	          FROM tickets WHERE id = $1`
-	var seatID, passengerID sql.NullInt64
-	err := r.db.QueryRow(query, id).Scan(&ticket.ID, &ticket.OrderID, &seatID, &passengerID, 
-		&ticket.DepartureDate, &ticket.Price, &ticket.TicketNumber, &ticket.Status)
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE id = $1`
+	err := scanTicket(r.db.QueryRowContext(ctx, query, id), ticket)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	if seatID.Valid {
-		ticket.SeatID = &seatID.Int64
-	}
-	if passengerID.Valid {
-		ticket.PassengerID = &passengerID.Int64
+	return ticket, err
+}
+
+// GetByNumber finds a ticket by the number printed on it
+func (r *ticketRepository) GetByNumber(ctx context.Context, number string) (*models.Ticket, error) {
+	ticket := &models.Ticket{}
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE ticket_number = $1`
+	err := scanTicket(r.db.QueryRowContext(ctx, query, number), ticket)
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
 	return ticket, err
 }
 
-func (r *ticketRepository) GetByOrderID(orderID int64) ([]models.Ticket, error) {
-	query := `SELECT id, order_id, seat_id, passenger_id, departure_date, price, ticket_number, status 
-	          FROM tickets WHERE order_id = $1 ORDER BY id`
-	rows, err := r.db.Query(query, orderID)
+func (r *ticketRepository) GetByOrderID(ctx context.Context, orderID int64) ([]models.Ticket, error) {
+	query := `SELECT ` + ticketColumns + ` FROM tickets WHERE order_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -51,25 +69,39 @@ func (r *ticketRepository) GetByOrderID(orderID int64) ([]models.Ticket, error)
 	var tickets []models.Ticket
 	for rows.Next() {
 		var ticket models.Ticket
-		var seatID, passengerID sql.NullInt64
-		if err := rows.Scan(&ticket.ID, &ticket.OrderID, &seatID, &passengerID, 
-			&ticket.DepartureDate, &ticket.Price, &ticket.TicketNumber, &ticket.Status); err != nil {
+		if err := scanTicket(rows, &ticket); err != nil {
 			return nil, err
 		}
-		if seatID.Valid {
-			ticket.SeatID = &seatID.Int64
-		}
-		if passengerID.Valid {
-			ticket.PassengerID = &passengerID.Int64
-		}
 		tickets = append(tickets, ticket)
 	}
 	return tickets, rows.Err()
 }
 
-func (r *ticketRepository) Update(ticket *models.Ticket) error {
+func (r *ticketRepository) Update(ctx context.Context, ticket *models.Ticket) error {
 	query := `UPDATE tickets SET status = $1 WHERE id = $2`
-	_, err := r.db.Exec(query, ticket.Status, ticket.ID)
+	_, err := r.db.ExecContext(ctx, query, ticket.Status, ticket.ID)
 	return err
 }
 
+// UpdateStatusByOrderID moves every ticket of the order that is in status
+// from to status to, returning how many tickets changed
+func (r *ticketRepository) UpdateStatusByOrderID(ctx context.Context, orderID int64, from, to models.TicketStatus) (int64, error) {
+	query := `UPDATE tickets SET status = $1 WHERE order_id = $2 AND status = $3`
+	result, err := r.db.ExecContext(ctx, query, to, orderID, from)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// HasUpcomingByRouteID reports whether the route has held or sold tickets
+// for trips that have not arrived yet
+func (r *ticketRepository) HasUpcomingByRouteID(ctx context.Context, routeID int64) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM tickets
+	              WHERE route_id = $1 AND status IN ('HELD', 'ACTIVE')
+	                AND COALESCE(arrives_at > NOW(), departure_date >= CURRENT_DATE))`
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, routeID).Scan(&exists)
+	return exists, err
+}
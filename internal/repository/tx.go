@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxTxAttempts bounds how many times WithTx runs a transaction that keeps
+// failing with serialization or deadlock errors
+const maxTxAttempts = 3
+
+// txRetryBackoff is the delay before the first retry; it doubles each time
+const txRetryBackoff = 20 * time.Millisecond
+
+// WithTx runs fn in a serializable transaction. The repositories passed to fn
+// are bound to the transaction, which is committed if fn returns nil and
+// rolled back otherwise. Serialization failures and deadlocks are retried, so
+// fn may run more than once and must not have side effects outside the
+// database.
+//
+// Calling WithTx on repositories that are already bound to a transaction
+// runs fn inside that transaction.
+func (r *Repositories) WithTx(ctx context.Context, fn func(tx *Repositories) error) error {
+	if r.db == nil {
+		return fn(r)
+	}
+
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := r.runTx(ctx, fn)
+		if err == nil || !isRetryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *Repositories) runTx(ctx context.Context, fn func(tx *Repositories) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(newRepositories(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pq.Error{Code: serializationFailure}, true},
+		{"deadlock", &pq.Error{Code: deadlockDetected}, true},
+		{"wrapped serialization failure", fmt.Errorf("create order: %w", &pq.Error{Code: serializationFailure}), true},
+		{"unique violation", &pq.Error{Code: uniqueViolation}, false},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithTx_BoundToTransaction(t *testing.T) {
+	// Repositories already bound to a transaction run fn directly
+	repos := newRepositories(nil)
+
+	called := false
+	err := repos.WithTx(context.Background(), func(tx *Repositories) error {
+		called = true
+		if tx != repos {
+			t.Error("Expected nested WithTx to reuse the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("Expected fn to be called")
+	}
+}
@@ -1,46 +1,87 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"github.com/lib/pq"
 	"github.com/project13/backend-stealthisproject/internal/models"
 )
 
 type userRepository struct {
-	db *sql.DB
+	db queryer
 }
 
 func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	query := `INSERT INTO users (email, password_hash, role) VALUES ($1, $2, $3) RETURNING id, created_at`
-	return r.db.QueryRow(query, user.Email, user.PasswordHash, user.Role).Scan(&user.ID, &user.CreatedAt)
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	query := `INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, user.Email, user.PasswordHash).Scan(&user.ID, &user.CreatedAt)
 }
 
-func (r *userRepository) GetByID(id int64) (*models.User, error) {
+const userColumns = `users.id, users.email, users.password_hash, users.email_verified_at, users.created_at,
+	ARRAY(SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	      WHERE ur.user_id = users.id ORDER BY r.name)`
+
+func scanUser(row interface{ Scan(...interface{}) error }, user *models.User) error {
+	var roles []string
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.EmailVerifiedAt, &user.CreatedAt, pq.Array(&roles))
+	if roles == nil {
+		roles = []string{}
+	}
+	user.Roles = roles
+	return err
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, role, created_at FROM users WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	err := scanUser(r.db.QueryRowContext(ctx, query, id), user)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return user, err
 }
 
-func (r *userRepository) GetByEmail(email string) (*models.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, role, created_at FROM users WHERE email = $1`
-	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	err := scanUser(r.db.QueryRowContext(ctx, query, email), user)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return user, err
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	query := `UPDATE users SET email = $1, password_hash = $2, role = $3 WHERE id = $4`
-	_, err := r.db.Exec(query, user.Email, user.PasswordHash, user.Role, user.ID)
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	query := `UPDATE users SET email = $1, password_hash = $2, email_verified_at = $3 WHERE id = $4`
+	_, err := r.db.ExecContext(ctx, query, user.Email, user.PasswordHash, user.EmailVerifiedAt, user.ID)
 	return err
 }
 
+// GetAll returns the users in order of registration, only those holding the
+// role when it isn't empty
+func (r *userRepository) GetAll(ctx context.Context, role string) ([]models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users
+	          WHERE $1 = '' OR EXISTS (
+	              SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	              WHERE ur.user_id = users.id AND r.name = $1)
+	          ORDER BY users.id`
+	rows, err := r.db.QueryContext(ctx, query, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
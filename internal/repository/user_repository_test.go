@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -37,10 +38,9 @@ func TestUserRepository_Create(t *testing.T) {
 	user := &models.User{
 		Email:        "test@example.com",
 		PasswordHash: "hashedpassword",
-		Role:         "PASSENGER",
 	}
 
-	err := repo.Create(user)
+	err := repo.Create(context.Background(), user)
 	if err != nil {
 		t.Fatalf("Failed to create user: %v", err)
 	}
@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	// Zone data is embedded so that station zones resolve on hosts without
+	// a zoneinfo database
+	_ "time/tzdata"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+// DateLayout is the format of travel dates in requests and in the tickets table
+const DateLayout = "2006-01-02"
+
+var (
+	ErrInvalidDate     = errors.New("date must be in YYYY-MM-DD format")
+	ErrDateInPast      = errors.New("travel date is in the past")
+	ErrBeyondHorizon   = errors.New("travel date is beyond the booking horizon")
+	ErrAlreadyDeparted = errors.New("train has already departed on this date")
+	ErrNotRunning      = errors.New("route does not run on this date")
+	ErrStopTimesOrder  = errors.New("stop times must not go backwards")
+	ErrInvalidClock    = errors.New("time must be in HH:MM or HH:MM:SS format")
+	ErrDuplicateStop   = errors.New("route calls at the same station twice")
+	ErrStopOrderGap    = errors.New("stop orders must run from 1 without gaps")
+	ErrFirstStopTimes  = errors.New("first stop must have a departure time and no arrival time")
+)
+
+// ParseDate parses a YYYY-MM-DD travel date as midnight UTC
+func ParseDate(s string) (time.Time, error) {
+	date, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	return date, nil
+}
+
+// ParseClock parses a time of day given as HH:MM or HH:MM:SS
+func ParseClock(s string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalidClock
+}
+
+var locations sync.Map
+
+// Location returns the IANA time zone called name. Unknown zones, which
+// ValidTimeZone keeps out of the database, fall back to UTC.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// ValidTimeZone reports whether name is an IANA time zone such as
+// "Europe/Minsk"
+func ValidTimeZone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// local combines the date dayOffset days after date with a time of day in
+// the given zone
+func local(date, clock time.Time, dayOffset int, zone string) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day()+dayOffset,
+		clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), Location(zone))
+}
+
+// Arrival returns when a run of the route that leaves its first station on
+// date arrives at stop, in the stop's time zone. It is zero if the stop has
+// no arrival time.
+func Arrival(date time.Time, stop models.RouteStation) time.Time {
+	if stop.ArrivalTime == nil {
+		return time.Time{}
+	}
+	return local(date, *stop.ArrivalTime, stop.ArrivalDayOffset, stop.TimeZone)
+}
+
+// Departure returns when a run of the route that leaves its first station on
+// date departs from stop, in the stop's time zone. It is zero if the stop has
+// no departure time.
+func Departure(date time.Time, stop models.RouteStation) time.Time {
+	if stop.DepartureTime == nil {
+		return time.Time{}
+	}
+	return local(date, *stop.DepartureTime, stop.DepartureDayOffset, stop.TimeZone)
+}
+
+// ValidateStops checks that the times of stops, given in stop order, never go
+// backwards: a train departs no earlier than it arrives and reaches each stop
+// after it left the previous one
+func ValidateStops(stops []models.RouteStation) error {
+	// Any date will do; only the order of the times matters
+	date := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var last time.Time
+	var lastStop int
+	started := false
+	for _, stop := range stops {
+		if stop.ArrivalDayOffset < 0 || stop.DepartureDayOffset < 0 {
+			return fmt.Errorf("%w: negative day offset at stop %d", ErrStopTimesOrder, stop.StopOrder)
+		}
+		if arrival := Arrival(date, stop); !arrival.IsZero() {
+			if started && !arrival.After(last) {
+				return fmt.Errorf("%w: stop %d is reached before stop %d is left", ErrStopTimesOrder, stop.StopOrder, lastStop)
+			}
+			last, lastStop, started = arrival, stop.StopOrder, true
+		}
+		if departure := Departure(date, stop); !departure.IsZero() {
+			sameStop := started && lastStop == stop.StopOrder
+			if sameStop && departure.Before(last) {
+				return fmt.Errorf("%w: stop %d is left before it is reached", ErrStopTimesOrder, stop.StopOrder)
+			}
+			if started && !sameStop && !departure.After(last) {
+				return fmt.Errorf("%w: stop %d is left before stop %d is left", ErrStopTimesOrder, stop.StopOrder, lastStop)
+			}
+			last, lastStop, started = departure, stop.StopOrder, true
+		}
+	}
+	return nil
+}
+
+// ValidateTimetable checks a route's complete list of stops, sorted by stop
+// order: stop orders run 1, 2, 3..., no station is called at twice, the train
+// starts at the first stop and its times never go backwards
+func ValidateTimetable(stops []models.RouteStation) error {
+	seen := make(map[int64]bool, len(stops))
+	for i, stop := range stops {
+		if stop.StopOrder != i+1 {
+			return fmt.Errorf("%w: expected stop %d, got %d", ErrStopOrderGap, i+1, stop.StopOrder)
+		}
+		if seen[stop.StationID] {
+			return fmt.Errorf("%w: station %d", ErrDuplicateStop, stop.StationID)
+		}
+		seen[stop.StationID] = true
+	}
+	if len(stops) > 0 && (stops[0].ArrivalTime != nil || stops[0].DepartureTime == nil) {
+		return ErrFirstStopTimes
+	}
+	return ValidateStops(stops)
+}
+
+// ValidateTravelDate checks that date lies between today and the end of the
+// booking horizon, both inclusive, where today is the date in zone, the time
+// zone of the station the train departs from
+func ValidateTravelDate(date, now time.Time, zone string, horizonDays int) error {
+	now = now.In(Location(zone))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if date.Before(today) {
+		return ErrDateInPast
+	}
+	if date.After(today.AddDate(0, 0, horizonDays)) {
+		return ErrBeyondHorizon
+	}
+	return nil
+}
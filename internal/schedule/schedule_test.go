@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+)
+
+func TestParseDate(t *testing.T) {
+	date, err := ParseDate("2025-03-14")
+	if err != nil {
+		t.Fatalf("Failed to parse date: %v", err)
+	}
+	if date.Year() != 2025 || date.Month() != time.March || date.Day() != 14 {
+		t.Errorf("Unexpected date %v", date)
+	}
+
+	if _, err := ParseDate("14.03.2025"); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("Expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func clockAt(hour, minute int) *time.Time {
+	t := time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+func TestArrival_DayOffset(t *testing.T) {
+	date := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	stop := models.RouteStation{ArrivalTime: clockAt(6, 30), ArrivalDayOffset: 1}
+
+	got := Arrival(date, stop)
+	want := time.Date(2025, 3, 15, 6, 30, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if !Departure(date, stop).IsZero() {
+		t.Error("Expected no departure from a stop without a departure time")
+	}
+}
+
+func TestDeparture_TimeZone(t *testing.T) {
+	date := time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC)
+	stop := models.RouteStation{DepartureTime: clockAt(1, 15), TimeZone: "Europe/Minsk"}
+
+	got := Departure(date, stop)
+	if got.Format(time.RFC3339) != "2025-01-14T01:15:00+03:00" {
+		t.Errorf("Expected local Minsk time, got %s", got.Format(time.RFC3339))
+	}
+	if want := time.Date(2025, 1, 13, 22, 15, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got.UTC())
+	}
+}
+
+func TestValidTimeZone(t *testing.T) {
+	if !ValidTimeZone("Europe/Vilnius") {
+		t.Error("Expected Europe/Vilnius to be valid")
+	}
+	for _, zone := range []string{"", "Local", "Europe/Atlantis"} {
+		if ValidTimeZone(zone) {
+			t.Errorf("Expected %q to be invalid", zone)
+		}
+	}
+	if Location("Europe/Atlantis") != time.UTC {
+		t.Error("Expected unknown zones to fall back to UTC")
+	}
+}
+
+func TestValidateStops(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops []models.RouteStation
+		ok    bool
+	}{
+		{"overnight", []models.RouteStation{
+			{StopOrder: 1, DepartureTime: clockAt(22, 0)},
+			{StopOrder: 2, ArrivalTime: clockAt(23, 50), DepartureTime: clockAt(0, 10), DepartureDayOffset: 1},
+			{StopOrder: 3, ArrivalTime: clockAt(6, 30), ArrivalDayOffset: 1},
+		}, true},
+		{"overnight without offset", []models.RouteStation{
+			{StopOrder: 1, DepartureTime: clockAt(22, 0)},
+			{StopOrder: 2, ArrivalTime: clockAt(6, 30)},
+		}, false},
+		{"departs before arriving", []models.RouteStation{
+			{StopOrder: 1, DepartureTime: clockAt(8, 0)},
+			{StopOrder: 2, ArrivalTime: clockAt(10, 0), DepartureTime: clockAt(9, 55)},
+		}, false},
+		{"same time at next stop", []models.RouteStation{
+			{StopOrder: 1, DepartureTime: clockAt(8, 0)},
+			{StopOrder: 2, ArrivalTime: clockAt(8, 0)},
+		}, false},
+		{"cross-border", []models.RouteStation{
+			{StopOrder: 1, DepartureTime: clockAt(22, 0), TimeZone: "Europe/Minsk"},
+			{StopOrder: 2, ArrivalTime: clockAt(21, 30), TimeZone: "Europe/Warsaw"},
+		}, true},
+		{"negative offset", []models.RouteStation{
+			{StopOrder: 1, DepartureTime: clockAt(8, 0), DepartureDayOffset: -1},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStops(tt.stops)
+			if tt.ok && err != nil {
+				t.Errorf("Expected valid stops, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrStopTimesOrder) {
+				t.Errorf("Expected ErrStopTimesOrder, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTimetable(t *testing.T) {
+	valid := func() []models.RouteStation {
+		return []models.RouteStation{
+			{StationID: 10, StopOrder: 1, DepartureTime: clockAt(8, 0)},
+			{StationID: 20, StopOrder: 2, ArrivalTime: clockAt(9, 0), DepartureTime: clockAt(9, 5)},
+			{StationID: 30, StopOrder: 3, ArrivalTime: clockAt(10, 0)},
+		}
+	}
+
+	if err := ValidateTimetable(valid()); err != nil {
+		t.Fatalf("Expected valid timetable, got %v", err)
+	}
+
+	gap := valid()
+	gap[2].StopOrder = 4
+	if err := ValidateTimetable(gap); !errors.Is(err, ErrStopOrderGap) {
+		t.Errorf("Expected ErrStopOrderGap, got %v", err)
+	}
+
+	duplicate := valid()
+	duplicate[2].StationID = 10
+	if err := ValidateTimetable(duplicate); !errors.Is(err, ErrDuplicateStop) {
+		t.Errorf("Expected ErrDuplicateStop, got %v", err)
+	}
+
+	arrival := valid()
+	arrival[0].ArrivalTime = clockAt(7, 55)
+	if err := ValidateTimetable(arrival); !errors.Is(err, ErrFirstStopTimes) {
+		t.Errorf("Expected ErrFirstStopTimes, got %v", err)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	for _, s := range []string{"08:30", "08:30:00"} {
+		clock, err := ParseClock(s)
+		if err != nil || clock.Hour() != 8 || clock.Minute() != 30 {
+			t.Errorf("Failed to parse %q: %v %v", s, clock, err)
+		}
+	}
+	if _, err := ParseClock("8.30"); !errors.Is(err, ErrInvalidClock) {
+		t.Errorf("Expected ErrInvalidClock, got %v", err)
+	}
+}
+
+func TestValidateTravelDate(t *testing.T) {
+	now := time.Date(2025, 3, 14, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		date time.Time
+		want error
+	}{
+		{"today", time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), nil},
+		{"yesterday", time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC), ErrDateInPast},
+		{"last day of horizon", time.Date(2025, 3, 24, 0, 0, 0, 0, time.UTC), nil},
+		{"beyond horizon", time.Date(2025, 3, 25, 0, 0, 0, 0, time.UTC), ErrBeyondHorizon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTravelDate(tt.date, now, "", 10); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateTravelDate_StationZone(t *testing.T) {
+	// 01:30 in Minsk is still the previous day in UTC
+	now := time.Date(2025, 3, 14, 22, 30, 0, 0, time.UTC)
+	yesterday := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	if err := ValidateTravelDate(yesterday, now, "Europe/Minsk", 10); !errors.Is(err, ErrDateInPast) {
+		t.Errorf("Expected ErrDateInPast in Minsk, got %v", err)
+	}
+	if err := ValidateTravelDate(yesterday, now, "", 10); err != nil {
+		t.Errorf("Expected the date to be today in UTC, got %v", err)
+	}
+	if err := ValidateTravelDate(time.Date(2025, 3, 25, 0, 0, 0, 0, time.UTC), now, "Europe/Minsk", 10); err != nil {
+		t.Errorf("Expected the horizon to count from the Minsk date, got %v", err)
+	}
+}
@@ -9,18 +9,34 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// DefaultAccessTTL is how long access tokens are valid unless configured
+// otherwise; sessions outlive them through refresh tokens
+const DefaultAccessTTL = 15 * time.Minute
+
 type AuthService struct {
-	userRepo repository.UserRepository
-	secret   string
+	userRepo  repository.UserRepository
+	keyring   *Keyring
+	accessTTL time.Duration
 }
 
-func NewAuthService(userRepo repository.UserRepository, secret string) *AuthService {
+// NewAuthService creates the service signing tokens with the keyring's active
+// key; a zero accessTTL means DefaultAccessTTL
+func NewAuthService(userRepo repository.UserRepository, keyring *Keyring, accessTTL time.Duration) *AuthService {
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTTL
+	}
 	return &AuthService{
-		userRepo: userRepo,
-		secret:   secret,
+		userRepo:  userRepo,
+		keyring:   keyring,
+		accessTTL: accessTTL,
 	}
 }
 
+// AccessTTL is how long the access tokens issued by the service are valid
+func (s *AuthService) AccessTTL() time.Duration {
+	return s.accessTTL
+}
+
 func (s *AuthService) HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -33,25 +49,48 @@ func (s *AuthService) VerifyPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-func (s *AuthService) GenerateToken(userID int64, role string) (string, error) {
+// Keyring is the set of keys tokens are signed and verified with
+func (s *AuthService) Keyring() *Keyring {
+	return s.keyring
+}
+
+// AccessToken is a signed access token with the ID and expiry it can be
+// revoked by
+type AccessToken struct {
+	Token     string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// GenerateToken signs an access token for a user's session carrying the
+// user's roles and the permissions they grant
+func (s *AuthService) GenerateToken(userID, sessionID int64, roles, permissions []string) (*AccessToken, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.accessTTL).Truncate(time.Second)
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"role":    role,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
-		"iat":     time.Now().Unix(),
+		"roles":   nonNil(roles),
+		"perms":   nonNil(permissions),
+		"sid":     sessionID,
+		"jti":     jti,
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secret))
+	token, err := s.keyring.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &AccessToken{Token: token, JTI: jti, ExpiresAt: expiresAt}, nil
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.secret), nil
-	})
+	token, err := jwt.Parse(tokenString, s.keyring.keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}))
 
 	if err != nil {
 		return nil, err
@@ -62,13 +101,30 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 		if !ok {
 			return nil, errors.New("invalid token claims")
 		}
-		role, ok := claims["role"].(string)
+		// Tokens without perms predate roles; refreshing replaces them
+		roles, ok := stringSlice(claims["roles"])
+		if !ok {
+			return nil, errors.New("invalid token claims")
+		}
+		permissions, ok := stringSlice(claims["perms"])
 		if !ok {
 			return nil, errors.New("invalid token claims")
 		}
+		// Tokens without a jti predate sessions and couldn't be revoked
+		sessionID, ok := claims["sid"].(float64)
+		if !ok {
+			return nil, errors.New("invalid token claims")
+		}
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			return nil, errors.New("invalid token claims")
+		}
 		return &Claims{
-			UserID: int64(userID),
-			Role:   role,
+			UserID:      int64(userID),
+			Roles:       roles,
+			Permissions: permissions,
+			SessionID:   int64(sessionID),
+			JTI:         jti,
 		}, nil
 	}
 
@@ -76,7 +132,44 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 }
 
 type Claims struct {
-	UserID int64
-	Role   string
+	UserID      int64
+	Roles       []string
+	Permissions []string
+	SessionID   int64
+	JTI         string
+}
+
+// HasPermission reports whether permissions contains permission
+func HasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// stringSlice converts a JSON array claim to strings
+func stringSlice(claim interface{}) ([]string, bool) {
+	items, ok := claim.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, s)
+	}
+	return list, true
 }
 
@@ -2,10 +2,22 @@ package auth
 
 import (
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
+func newTestService(t *testing.T) *AuthService {
+	t.Helper()
+	keyring, err := NewKeyring(DefaultKeyID, NewHMACKey(DefaultKeyID, []byte("test-secret")))
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+	return NewAuthService(nil, keyring, 0)
+}
+
 func TestHashPassword(t *testing.T) {
-	service := NewAuthService(nil, "test-secret")
+	service := newTestService(t)
 
 	password := "testpassword123"
 	hash, err := service.HashPassword(password)
@@ -23,7 +35,7 @@ func TestHashPassword(t *testing.T) {
 }
 
 func TestVerifyPassword(t *testing.T) {
-	service := NewAuthService(nil, "test-secret")
+	service := newTestService(t)
 
 	password := "testpassword123"
 	hash, err := service.HashPassword(password)
@@ -43,30 +55,37 @@ func TestVerifyPassword(t *testing.T) {
 }
 
 func TestGenerateToken(t *testing.T) {
-	service := NewAuthService(nil, "test-secret")
+	service := newTestService(t)
 
-	token, err := service.GenerateToken(1, "PASSENGER")
+	token, err := service.GenerateToken(1, 1, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
 
-	if token == "" {
+	if token.Token == "" {
 		t.Error("Token should not be empty")
 	}
+	if token.JTI == "" {
+		t.Error("Token should have a jti")
+	}
+	if !token.ExpiresAt.After(time.Now()) {
+		t.Errorf("Expected the token to expire in the future, got %v", token.ExpiresAt)
+	}
 }
 
 func TestValidateToken(t *testing.T) {
-	service := NewAuthService(nil, "test-secret")
+	service := newTestService(t)
 
 	userID := int64(123)
-	role := "PASSENGER"
+	roles := []string{"support"}
+	permissions := []string{"orders:read", "users:read"}
 
-	token, err := service.GenerateToken(userID, role)
+	token, err := service.GenerateToken(userID, 7, roles, permissions)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
 
-	claims, err := service.ValidateToken(token)
+	claims, err := service.ValidateToken(token.Token)
 	if err != nil {
 		t.Fatalf("Failed to validate token: %v", err)
 	}
@@ -75,8 +94,92 @@ func TestValidateToken(t *testing.T) {
 		t.Errorf("Expected user ID %d, got %d", userID, claims.UserID)
 	}
 
-	if claims.Role != role {
-		t.Errorf("Expected role %s, got %s", role, claims.Role)
+	if len(claims.Roles) != 1 || claims.Roles[0] != "support" {
+		t.Errorf("Expected roles %v, got %v", roles, claims.Roles)
+	}
+
+	if !HasPermission(claims.Permissions, "users:read") || HasPermission(claims.Permissions, "users:write") {
+		t.Errorf("Expected permissions %v, got %v", permissions, claims.Permissions)
+	}
+
+	if claims.SessionID != 7 || claims.JTI != token.JTI {
+		t.Errorf("Expected session 7 and jti %s, got %d and %s", token.JTI, claims.SessionID, claims.JTI)
+	}
+}
+
+func TestValidateToken_WithoutJTI(t *testing.T) {
+	service := newTestService(t)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"roles":   []string{},
+		"perms":   []string{},
+		"sid":     1,
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := service.ValidateToken(token); err == nil {
+		t.Error("Expected token without jti to be rejected")
+	}
+}
+
+func TestValidateToken_WithoutPermissions(t *testing.T) {
+	service := newTestService(t)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"role":    "ADMIN",
+		"sid":     1,
+		"jti":     "abc",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := service.ValidateToken(token); err == nil {
+		t.Error("Expected token issued before roles to be rejected")
+	}
+}
+
+func TestValidateToken_Expired(t *testing.T) {
+	service := newTestService(t)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"roles":   []string{},
+		"perms":   []string{},
+		"sid":     1,
+		"jti":     "abc",
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := service.ValidateToken(token); err == nil {
+		t.Error("Expected expired token to be rejected")
 	}
 }
 
+func TestNewToken(t *testing.T) {
+	token, hash, err := NewToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if token == "" || hash == "" || token == hash {
+		t.Errorf("Expected distinct token and hash, got %q and %q", token, hash)
+	}
+	if HashToken(token) != hash {
+		t.Error("Expected the token to hash to the returned hash")
+	}
+
+	other, _, _ := NewToken()
+	if other == token {
+		t.Error("Expected tokens to be random")
+	}
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms a key can use
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// DefaultKeyID is the kid of the HMAC key made from JWT_SECRET. Tokens
+// without a kid header are checked against it.
+const DefaultKeyID = "default"
+
+// ErrEmptySecret is returned for an HMAC key without a secret, which would
+// let anyone sign tokens
+var ErrEmptySecret = errors.New("HMAC key with an empty secret")
+
+// Key is a signing or verification key of the keyring. Keys loaded from a
+// public key can only verify.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey returns an HS256 key that both signs and verifies with secret
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}
+}
+
+// ParseKey reads an RS256 or EdDSA key from PEM. A private key signs and
+// verifies; a public key only verifies.
+func ParseKey(id, algorithm string, data []byte) (*Key, error) {
+	key := &Key{ID: id, Algorithm: algorithm}
+	private := strings.Contains(string(data), "PRIVATE KEY")
+
+	var err error
+	switch algorithm {
+	case AlgRS256:
+		if private {
+			var k *rsa.PrivateKey
+			if k, err = jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+				key.signKey, key.verifyKey = k, &k.PublicKey
+			}
+		} else {
+			key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		}
+	case AlgEdDSA:
+		if private {
+			var k crypto.PrivateKey
+			if k, err = jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+				priv := k.(ed25519.PrivateKey)
+				key.signKey, key.verifyKey = priv, priv.Public()
+			}
+		} else {
+			key.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(data)
+		}
+	default:
+		return nil, fmt.Errorf("key %s: unsupported algorithm %q", id, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+	return key, nil
+}
+
+// CanSign reports whether the key holds the private half
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// Keyring holds the key tokens are signed with and every key they are
+// verified with. Rotating keys means adding the new key, making it active and
+// dropping the old one once the tokens it signed have expired.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+	order  []string
+}
+
+// NewKeyring builds a keyring that signs with the key activeID
+func NewKeyring(activeID string, keys ...*Key) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("key without an ID")
+		}
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		if secret, ok := key.verifyKey.([]byte); ok && len(secret) == 0 {
+			return nil, fmt.Errorf("key %s: %w", key.ID, ErrEmptySecret)
+		}
+		k.keys[key.ID] = key
+		k.order = append(k.order, key.ID)
+	}
+
+	k.active = k.keys[activeID]
+	if k.active == nil {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeID)
+	}
+	if !k.active.CanSign() {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	return k, nil
+}
+
+// LoadKeyring builds the keyring from configuration: the HMAC secret under
+// DefaultKeyID, plus keys listed in spec as comma-separated kid:alg:path
+// entries pointing to PEM files. activeID names the signing key; empty means
+// the HMAC key. An empty secret leaves the HMAC key out, which only works
+// with another active key; tokens without a kid are then refused.
+func LoadKeyring(secret, spec, activeID string) (*Keyring, error) {
+	if activeID == "" {
+		activeID = DefaultKeyID
+	}
+	if secret == "" && activeID == DefaultKeyID {
+		return nil, fmt.Errorf("key %s: %w", DefaultKeyID, ErrEmptySecret)
+	}
+
+	var keys []*Key
+	if secret != "" {
+		keys = append(keys, NewHMACKey(DefaultKeyID, []byte(secret)))
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key entry %q, want kid:alg:path", entry)
+		}
+		data, err := os.ReadFile(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", parts[0], err)
+		}
+		key, err := ParseKey(parts[0], parts[1], data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyring(activeID, keys...)
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method(), claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.signKey)
+}
+
+// keyFunc picks the verification key by the token's kid and refuses tokens
+// whose algorithm isn't the key's, so a public key can't be used as an HMAC
+// secret
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA modulus and exponent
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 curve and public key
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes the public keys of the keyring. HMAC keys are secret and
+// left out, so tokens signed with them can't be verified by others.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range k.order {
+		key := k.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
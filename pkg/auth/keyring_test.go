@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func rsaKey(t *testing.T, id string) *Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("Failed to marshal RSA key: %v", err)
+	}
+	key, err := ParseKey(id, AlgRS256, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to parse RSA key: %v", err)
+	}
+	return key
+}
+
+func edKeyPEM(t *testing.T) (private, public []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to marshal Ed25519 key: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to marshal Ed25519 key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+func serviceWith(t *testing.T, activeID string, keys ...*Key) *AuthService {
+	t.Helper()
+	keyring, err := NewKeyring(activeID, keys...)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+	return NewAuthService(nil, keyring, 0)
+}
+
+func TestKeyring_SignAndVerify(t *testing.T) {
+	edPrivate, _ := edKeyPEM(t)
+	edKey, err := ParseKey("ed", AlgEdDSA, edPrivate)
+	if err != nil {
+		t.Fatalf("Failed to parse Ed25519 key: %v", err)
+	}
+
+	keys := []*Key{NewHMACKey("hs", []byte("test-secret")), rsaKey(t, "rsa"), edKey}
+	for _, key := range keys {
+		service := serviceWith(t, key.ID, keys...)
+		token, err := service.GenerateToken(1, 1, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to generate token: %v", key.ID, err)
+		}
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token.Token, jwt.MapClaims{})
+		if err != nil {
+			t.Fatalf("%s: failed to parse token: %v", key.ID, err)
+		}
+		if parsed.Header["kid"] != key.ID || parsed.Method.Alg() != key.Algorithm {
+			t.Errorf("Expected kid %s and alg %s, got %v and %s", key.ID, key.Algorithm, parsed.Header["kid"], parsed.Method.Alg())
+		}
+		if _, err := service.ValidateToken(token.Token); err != nil {
+			t.Errorf("%s: failed to validate token: %v", key.ID, err)
+		}
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey := NewHMACKey(DefaultKeyID, []byte("test-secret"))
+	newKey := rsaKey(t, "2026-01")
+
+	before := serviceWith(t, oldKey.ID, oldKey)
+	token, err := before.GenerateToken(1, 1, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	after := serviceWith(t, newKey.ID, oldKey, newKey)
+	if _, err := after.ValidateToken(token.Token); err != nil {
+		t.Errorf("Expected a token of the previous key to stay valid: %v", err)
+	}
+
+	retired := serviceWith(t, newKey.ID, newKey)
+	if _, err := retired.ValidateToken(token.Token); err == nil {
+		t.Error("Expected a token of a removed key to be rejected")
+	}
+}
+
+func TestKeyring_AlgorithmMismatch(t *testing.T) {
+	rsa := rsaKey(t, "rsa")
+	service := serviceWith(t, rsa.ID, rsa)
+
+	// An HS256 token claiming the RSA kid must not be checked as HMAC
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"roles":   []string{},
+		"perms":   []string{},
+		"sid":     1,
+		"jti":     "x",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = rsa.ID
+	signed, err := token.SignedString([]byte("anything"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := service.ValidateToken(signed); err == nil {
+		t.Error("Expected a token with the wrong algorithm for its key to be rejected")
+	}
+}
+
+func TestNewKeyring_ActiveKeyMustSign(t *testing.T) {
+	_, edPublic := edKeyPEM(t)
+	verifyOnly, err := ParseKey("partner", AlgEdDSA, edPublic)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+
+	if _, err := NewKeyring("partner", verifyOnly); err == nil {
+		t.Error("Expected a public key to be refused as the active key")
+	}
+	if _, err := NewKeyring("missing", verifyOnly); err == nil {
+		t.Error("Expected an unknown active key to be refused")
+	}
+	if _, err := NewKeyring("partner", verifyOnly, verifyOnly); err == nil {
+		t.Error("Expected duplicate key IDs to be refused")
+	}
+}
+
+func TestKeyring_JWKS(t *testing.T) {
+	edPrivate, edPublic := edKeyPEM(t)
+	edKey, err := ParseKey("ed", AlgEdDSA, edPrivate)
+	if err != nil {
+		t.Fatalf("Failed to parse Ed25519 key: %v", err)
+	}
+	edVerify, err := ParseKey("ed-old", AlgEdDSA, edPublic)
+	if err != nil {
+		t.Fatalf("Failed to parse Ed25519 key: %v", err)
+	}
+
+	keyring, err := NewKeyring("ed", NewHMACKey(DefaultKeyID, []byte("test-secret")), rsaKey(t, "rsa"), edKey, edVerify)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+
+	set := keyring.JWKS()
+	if len(set.Keys) != 3 {
+		t.Fatalf("Expected 3 public keys, got %d", len(set.Keys))
+	}
+	for _, jwk := range set.Keys {
+		switch jwk.KeyID {
+		case "rsa":
+			if jwk.KeyType != "RSA" || jwk.N == "" || jwk.E != "AQAB" {
+				t.Errorf("Unexpected RSA key %+v", jwk)
+			}
+		case "ed", "ed-old":
+			if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" || jwk.X == "" {
+				t.Errorf("Unexpected Ed25519 key %+v", jwk)
+			}
+		default:
+			t.Errorf("Unexpected key %q in the set", jwk.KeyID)
+		}
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	edPrivate, _ := edKeyPEM(t)
+	path := filepath.Join(t.TempDir(), "ed.pem")
+	if err := os.WriteFile(path, edPrivate, 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	keyring, err := LoadKeyring("test-secret", "ed:EdDSA:"+path, "")
+	if err != nil {
+		t.Fatalf("Failed to load keyring: %v", err)
+	}
+	if keyring.active.ID != DefaultKeyID {
+		t.Errorf("Expected the secret to sign by default, got %s", keyring.active.ID)
+	}
+
+	keyring, err = LoadKeyring("test-secret", " ed:EdDSA:"+path+" ,", "ed")
+	if err != nil {
+		t.Fatalf("Failed to load keyring: %v", err)
+	}
+	if keyring.active.ID != "ed" {
+		t.Errorf("Expected ed to sign, got %s", keyring.active.ID)
+	}
+
+	for _, spec := range []string{"ed:" + path, "ed:RS256:" + path, "ed:HS512:" + path, "ed:EdDSA:/missing.pem"} {
+		if _, err := LoadKeyring("test-secret", spec, ""); err == nil {
+			t.Errorf("Expected %q to be refused", spec)
+		}
+	}
+}
+
+func TestLoadKeyring_WithoutSecret(t *testing.T) {
+	edPrivate, _ := edKeyPEM(t)
+	path := filepath.Join(t.TempDir(), "ed.pem")
+	if err := os.WriteFile(path, edPrivate, 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	keyring, err := LoadKeyring("", "ed:EdDSA:"+path, "ed")
+	if err != nil {
+		t.Fatalf("Failed to load keyring: %v", err)
+	}
+	if _, ok := keyring.keys[DefaultKeyID]; ok {
+		t.Fatal("Expected no HMAC key without a secret")
+	}
+
+	service := NewAuthService(nil, keyring, 0)
+	token, err := service.GenerateToken(1, 2, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if _, err := service.ValidateToken(token.Token); err != nil {
+		t.Errorf("Failed to validate token: %v", err)
+	}
+
+	// A token without a kid would need the HMAC key
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1, "roles": []string{}, "perms": []string{}, "sid": 2, "jti": "abc",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	forged, err := unsigned.SignedString([]byte(""))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	if _, err := service.ValidateToken(forged); err == nil {
+		t.Error("Expected a token without a kid to be refused")
+	}
+}
+
+func TestLoadKeyring_EmptySecret(t *testing.T) {
+	edPrivate, _ := edKeyPEM(t)
+	path := filepath.Join(t.TempDir(), "ed.pem")
+	if err := os.WriteFile(path, edPrivate, 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	for _, activeID := range []string{"", DefaultKeyID} {
+		if _, err := LoadKeyring("", "ed:EdDSA:"+path, activeID); !errors.Is(err, ErrEmptySecret) {
+			t.Errorf("Expected ErrEmptySecret with active key %q, got %v", activeID, err)
+		}
+	}
+	if _, err := NewKeyring("hs", NewHMACKey("hs", nil)); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("Expected ErrEmptySecret, got %v", err)
+	}
+}
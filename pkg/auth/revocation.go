@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+// RevocationList keeps the jtis of revoked access tokens in memory so that
+// requests can be checked without a database round trip. Revocations made by
+// this process apply at once; those of other instances arrive with the next
+// sync from the database.
+type RevocationList struct {
+	sessions repository.SessionRepository
+	interval time.Duration
+
+	mu   sync.RWMutex
+	jtis map[string]time.Time
+}
+
+func NewRevocationList(sessions repository.SessionRepository, interval time.Duration) *RevocationList {
+	return &RevocationList{
+		sessions: sessions,
+		interval: interval,
+		jtis:     make(map[string]time.Time),
+	}
+}
+
+// Revoke adds revoked tokens to the list
+func (l *RevocationList) Revoke(tokens ...models.RevokedToken) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, t := range tokens {
+		l.jtis[t.JTI] = t.ExpiresAt
+	}
+}
+
+// IsRevoked reports whether the token with the jti was revoked
+func (l *RevocationList) IsRevoked(jti string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.jtis[jti]
+	return ok
+}
+
+// Run reloads the list from the database every interval until ctx is
+// cancelled, dropping tokens that have expired anyway
+func (l *RevocationList) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.sync(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *RevocationList) sync(ctx context.Context) {
+	if _, err := l.sessions.PurgeRevokedTokens(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("Failed to purge expired revoked tokens: %v", err)
+	}
+	tokens, err := l.sessions.GetRevokedTokens(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to load revoked tokens: %v", err)
+		}
+		return
+	}
+
+	jtis := make(map[string]time.Time, len(tokens))
+	for _, t := range tokens {
+		jtis[t.JTI] = t.ExpiresAt
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Keep local revocations the database read may have raced with
+	for jti, expiresAt := range l.jtis {
+		if _, ok := jtis[jti]; !ok && expiresAt.After(now) {
+			jtis[jti] = expiresAt
+		}
+	}
+	l.jtis = jtis
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project13/backend-stealthisproject/internal/models"
+	"github.com/project13/backend-stealthisproject/internal/repository"
+)
+
+type fakeSessionRepository struct {
+	repository.SessionRepository
+	revoked []models.RevokedToken
+}
+
+func (f *fakeSessionRepository) GetRevokedTokens(ctx context.Context) ([]models.RevokedToken, error) {
+	return f.revoked, nil
+}
+
+func (f *fakeSessionRepository) PurgeRevokedTokens(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestRevocationList_Sync(t *testing.T) {
+	later := time.Now().Add(time.Minute)
+	sessions := &fakeSessionRepository{revoked: []models.RevokedToken{{JTI: "elsewhere", ExpiresAt: later}}}
+	list := NewRevocationList(sessions, time.Minute)
+
+	list.Revoke(models.RevokedToken{JTI: "local", ExpiresAt: later})
+	list.Revoke(models.RevokedToken{JTI: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+	if !list.IsRevoked("local") {
+		t.Error("Expected a local revocation to apply at once")
+	}
+
+	list.sync(context.Background())
+
+	if !list.IsRevoked("elsewhere") {
+		t.Error("Expected revocations from the database to be loaded")
+	}
+	if !list.IsRevoked("local") {
+		t.Error("Expected a local revocation to survive the sync")
+	}
+	if list.IsRevoked("expired") {
+		t.Error("Expected an expired token to be dropped")
+	}
+	if list.IsRevoked("valid") {
+		t.Error("Expected a token that wasn't revoked to pass")
+	}
+}